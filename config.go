@@ -0,0 +1,330 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Префикс переменных окружения, опечатки в которых стоит подсветить
+const configEnvPrefix = "TASKAPI_"
+
+// Настройки сервиса
+type Config struct {
+	AllowPastDue        bool          // Разрешать срок выполнения в прошлом
+	MaxImportTasks      int           // Максимум задач в одном импорте
+	SyncLogging         bool          // Писать лог синхронно, минуя канал
+	LogThrottleInterval time.Duration // Минимальный интервал между одинаковыми сообщениями
+	IdempotencyWait     bool          // Ждать параллельный запрос с тем же ключом вместо 409
+	IdempotencyTTL      time.Duration // Время хранения ключа идемпотентности
+	IdempotencyKeys     int           // Максимальное число хранимых ключей идемпотентности
+	FallbackToJSON      bool          // Отвечать JSON при неподдерживаемом Accept вместо 406
+	VersionHeader       bool          // Добавлять X-App-Version к ответам
+	RequireRequestID    bool          // Отклонять запросы без X-Request-ID
+	EnableUI            bool          // Включить HTML-страницу /ui
+	StorageModel        string        // Модель хранилища: mutex или actor
+	NormalizeTitles     bool          // Схлопывать пробелы в названиях задач
+	LogHighWater        int           // Заполненность канала логов в % для предупреждения (0 — выкл.)
+	ValidationStatus    int           // Код ответа при ошибках валидации: 400 или 422
+	SnapshotTTL         time.Duration // Время жизни снимка для чтения
+	MaxLogMessageBytes  int           // Максимальная длина сообщения лога в байтах
+	FiltersHeader       bool          // Сообщать применённые фильтры в X-Applied-Filters
+	SeedFile            string        // JSON-файл с начальными задачами
+	AdminEndpoints      bool          // Включить служебные эндпоинты
+	StrictJSON          bool          // Отклонять данные после JSON-тела запроса
+	ServerAddr          string        // Адрес HTTP-сервера
+	ListenRetries       int           // Повторные попытки занять адрес (0 — без повторов)
+	LogFormat           string        // Формат записей лога: text или logfmt
+	AllowBlankTitles    bool          // Разрешать названия только из пробельных символов
+	MaxResponseBytes    int           // Максимальный размер ответа со списком (0 — без ограничения)
+	MaxExportTasks      int           // Максимальное число задач в выгрузке (0 — без ограничения)
+	ServerTiming        bool          // Добавлять заголовок Server-Timing
+	WarnDueDays         int           // Предупреждать о сроке дальше N дней (0 — выкл.)
+	WarnTitleLength     int           // Предупреждать о названии длиннее N символов (0 — выкл.)
+	ImportWait          bool          // Ждать завершения параллельного импорта вместо 409
+	DoneTitlePrefix     string        // Префикс названия, создающий выполненную задачу
+	LogSampleRate       int           // Писать в лог 1 из N сообщений (1 — все)
+	MaxDecodedBody      int64         // Максимальный размер распакованного gzip-тела в байтах
+	// Направления сортировки по умолчанию для каждого поля
+	SortDefaults map[string]string
+}
+
+// Загрузка настроек из переменных окружения (в формате os.Environ).
+// Проверяются все переменные сразу: возвращаемая ошибка объединяет
+// все некорректные значения, а предупреждения сообщают о неизвестных
+// переменных с префиксом TASKAPI_ и о похожих на известные опечатках.
+// Каждую настройку можно задать и с префиксом: TASKAPI_SYNC_LOGGING
+// имеет приоритет над SYNC_LOGGING.
+func LoadConfig(environ []string) (Config, []string, error) {
+	l := newEnvLoader(environ)
+	config := Config{
+		AllowPastDue:        l.bool("ALLOW_PAST_DUE", true),
+		MaxImportTasks:      l.positiveInt("MAX_IMPORT_TASKS", 10000),
+		SyncLogging:         l.bool("SYNC_LOGGING", false),
+		LogThrottleInterval: l.duration("LOG_THROTTLE_INTERVAL", 0),
+		IdempotencyWait:     l.choice("IDEMPOTENCY_CONCURRENT", "wait", "wait", "reject") == "wait",
+		IdempotencyTTL:      l.positiveDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		IdempotencyKeys:     l.positiveInt("IDEMPOTENCY_MAX_KEYS", 10000),
+		FallbackToJSON:      l.bool("FALLBACK_TO_JSON", true),
+		VersionHeader:       l.bool("VERSION_HEADER", false),
+		RequireRequestID:    l.bool("REQUIRE_REQUEST_ID", false),
+		EnableUI:            l.bool("ENABLE_UI", false),
+		NormalizeTitles:     l.bool("NORMALIZE_TITLES", false),
+		LogHighWater:        l.percent("LOG_HIGH_WATER_PERCENT", 80),
+		FiltersHeader:       l.bool("APPLIED_FILTERS_HEADER", false),
+		SeedFile:            l.string("SEED_FILE"),
+		AdminEndpoints:      l.bool("ENABLE_ADMIN_ENDPOINTS", false),
+		StrictJSON:          l.bool("STRICT_JSON", true),
+		ServerAddr:          l.stringDefault("SERVER_ADDR", ":8080"),
+		ListenRetries:       l.nonNegativeInt("LISTEN_RETRIES", 0),
+		LogFormat:           l.choice("LOG_FORMAT", "text", "text", "logfmt"),
+		AllowBlankTitles:    l.bool("ALLOW_BLANK_TITLES", false),
+		MaxResponseBytes:    l.nonNegativeInt("MAX_RESPONSE_BYTES", 0),
+		MaxExportTasks:      l.nonNegativeInt("MAX_EXPORT_TASKS", 0),
+		ServerTiming:        l.bool("SERVER_TIMING", false),
+		WarnDueDays:         l.nonNegativeInt("WARN_DUE_DAYS", 365),
+		WarnTitleLength:     l.nonNegativeInt("WARN_TITLE_LENGTH", 200),
+		ImportWait:          l.choice("IMPORT_CONCURRENT", "wait", "wait", "reject") == "wait",
+		DoneTitlePrefix:     l.string("DONE_TITLE_PREFIX"),
+		LogSampleRate:       l.positiveInt("LOG_SAMPLE_RATE", 1),
+		MaxDecodedBody:      int64(l.positiveInt("MAX_DECODED_BODY_BYTES", 32<<20)),
+		MaxLogMessageBytes:  l.positiveInt("MAX_LOG_MESSAGE_BYTES", 4096),
+		SnapshotTTL:         l.positiveDuration("SNAPSHOT_TTL", 5*time.Minute),
+		ValidationStatus:    l.httpStatus("VALIDATION_ERROR_STATUS", http.StatusBadRequest, http.StatusBadRequest, http.StatusUnprocessableEntity),
+		StorageModel:        l.choice("STORAGE_MODEL", "mutex", "mutex", "actor"),
+		SortDefaults:        l.sortDefaults("SORT_DEFAULTS"),
+	}
+	return config, l.warnings(), errors.Join(l.errors...)
+}
+
+// Разбор переменных окружения с накоплением ошибок
+type envLoader struct {
+	env    map[string]string
+	known  map[string]bool   // Имена настроек без префикса
+	source map[string]string // Переменная, из которой прочитана настройка
+	errors []error
+}
+
+func newEnvLoader(environ []string) *envLoader {
+	l := &envLoader{env: make(map[string]string), known: make(map[string]bool), source: make(map[string]string)}
+	for _, entry := range environ {
+		if name, value, ok := strings.Cut(entry, "="); ok {
+			l.env[name] = value
+		}
+	}
+	return l
+}
+
+// Значение переменной; пустое значение равносильно отсутствию.
+// Переменная с префиксом TASKAPI_ имеет приоритет над именем без префикса.
+func (l *envLoader) lookup(name string) (string, bool) {
+	l.known[name] = true
+	for _, source := range []string{configEnvPrefix + name, name} {
+		if value := l.env[source]; value != "" {
+			l.source[name] = source
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// Имя переменной, из которой прочитана настройка, для сообщений об ошибках
+func (l *envLoader) sourceOf(name string) string {
+	if source, ok := l.source[name]; ok {
+		return source
+	}
+	return name
+}
+
+func (l *envLoader) fail(name, value, expected string) {
+	l.errors = append(l.errors, fmt.Errorf("%s=%q: ожидается %s", l.sourceOf(name), value, expected))
+}
+
+func (l *envLoader) string(name string) string {
+	value, _ := l.lookup(name)
+	return value
+}
+
+func (l *envLoader) stringDefault(name, def string) string {
+	if value, ok := l.lookup(name); ok {
+		return value
+	}
+	return def
+}
+
+func (l *envLoader) bool(name string, def bool) bool {
+	value, ok := l.lookup(name)
+	if !ok {
+		return def
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		l.fail(name, value, "true или false")
+		return def
+	}
+	return parsed
+}
+
+func (l *envLoader) positiveInt(name string, def int) int {
+	value, ok := l.lookup(name)
+	if !ok {
+		return def
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		l.fail(name, value, "положительное целое число")
+		return def
+	}
+	return parsed
+}
+
+func (l *envLoader) nonNegativeInt(name string, def int) int {
+	value, ok := l.lookup(name)
+	if !ok {
+		return def
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		l.fail(name, value, "неотрицательное целое число")
+		return def
+	}
+	return parsed
+}
+
+func (l *envLoader) percent(name string, def int) int {
+	value, ok := l.lookup(name)
+	if !ok {
+		return def
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 || parsed > 100 {
+		l.fail(name, value, "целое число от 0 до 100")
+		return def
+	}
+	return parsed
+}
+
+func (l *envLoader) httpStatus(name string, def int, options ...int) int {
+	names := make([]string, len(options))
+	for i, option := range options {
+		names[i] = strconv.Itoa(option)
+	}
+	status, _ := strconv.Atoi(l.choice(name, strconv.Itoa(def), names...))
+	return status
+}
+
+func (l *envLoader) duration(name string, def time.Duration) time.Duration {
+	value, ok := l.lookup(name)
+	if !ok {
+		return def
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		l.fail(name, value, "неотрицательная длительность, например 10s")
+		return def
+	}
+	return parsed
+}
+
+func (l *envLoader) positiveDuration(name string, def time.Duration) time.Duration {
+	value, ok := l.lookup(name)
+	if !ok {
+		return def
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		l.fail(name, value, "положительная длительность, например 5m")
+		return def
+	}
+	return parsed
+}
+
+func (l *envLoader) choice(name, def string, options ...string) string {
+	value, ok := l.lookup(name)
+	if !ok {
+		return def
+	}
+	if !slices.Contains(options, value) {
+		l.fail(name, value, "одно из: "+strings.Join(options, ", "))
+		return def
+	}
+	return value
+}
+
+func (l *envLoader) sortDefaults(name string) map[string]string {
+	value, _ := l.lookup(name)
+	orders, err := ParseSortDefaults(value)
+	if err != nil {
+		l.errors = append(l.errors, fmt.Errorf("%s=%q: %v", l.sourceOf(name), value, err))
+		orders, _ = ParseSortDefaults("")
+	}
+	return orders
+}
+
+// Предупреждения о неизвестных переменных: с префиксом TASKAPI_ — всегда,
+// без префикса — только если имя отличается от известной настройки
+// на одну-две правки (SYNC_LOGING вместо SYNC_LOGGING)
+func (l *envLoader) warnings() []string {
+	var warnings []string
+	for name := range l.env {
+		base, prefixed := strings.CutPrefix(name, configEnvPrefix)
+		if l.known[base] {
+			continue
+		}
+		suggestion := l.closestKnown(base)
+		if !prefixed && suggestion == "" {
+			continue
+		}
+		warning := "неизвестная переменная " + name + ", возможна опечатка"
+		if suggestion != "" {
+			if prefixed {
+				suggestion = configEnvPrefix + suggestion
+			}
+			warning += "; возможно, имелась в виду " + suggestion
+		}
+		warnings = append(warnings, warning)
+	}
+	slices.Sort(warnings)
+	return warnings
+}
+
+// Ближайшая известная настройка на расстоянии не более двух правок
+// (одной для коротких имён, чтобы не цеплять посторонние переменные)
+func (l *envLoader) closestKnown(name string) string {
+	limit := 2
+	if len(name) < 8 {
+		limit = 1
+	}
+	best, bestDistance := "", limit+1
+	for known := range l.known {
+		if distance := editDistance(name, known); distance < bestDistance || distance == bestDistance && known < best {
+			best, bestDistance = known, distance
+		}
+	}
+	return best
+}
+
+// Расстояние Левенштейна между строками
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigPrefixedNames(t *testing.T) {
+	config, _, err := LoadConfig([]string{"SYNC_LOGGING=false", "TASKAPI_SYNC_LOGGING=true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.SyncLogging {
+		t.Error("TASKAPI_SYNC_LOGGING did not take precedence over SYNC_LOGGING")
+	}
+	_, _, err = LoadConfig([]string{"TASKAPI_MAX_IMPORT_TASKS=abc"})
+	if err == nil || !strings.Contains(err.Error(), "TASKAPI_MAX_IMPORT_TASKS") {
+		t.Errorf("err = %v, want it to name TASKAPI_MAX_IMPORT_TASKS", err)
+	}
+}
+
+func TestLoadConfigTypoWarnings(t *testing.T) {
+	tests := []struct {
+		entry, want string
+	}{
+		{"SYNC_LOGING=true", "возможно, имелась в виду SYNC_LOGGING"},
+		{"TASKAPI_SYNC_LOGING=true", "возможно, имелась в виду TASKAPI_SYNC_LOGGING"},
+		{"TASKAPI_UNRELATED=1", "неизвестная переменная TASKAPI_UNRELATED"},
+	}
+	for _, tt := range tests {
+		_, warnings, err := LoadConfig([]string{tt.entry})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(warnings) != 1 || !strings.Contains(warnings[0], tt.want) {
+			t.Errorf("%s: warnings = %q, want %q", tt.entry, warnings, tt.want)
+		}
+	}
+	for _, entry := range []string{"PATH=/usr/bin", "HOME=/root", "SYNC_LOGGING=true", "TASKAPI_SYNC_LOGGING=true"} {
+		if _, warnings, _ := LoadConfig([]string{entry}); len(warnings) != 0 {
+			t.Errorf("%s: unexpected warnings %q", entry, warnings)
+		}
+	}
+}
+
+func TestLoadConfigReportsAllErrors(t *testing.T) {
+	bad := []string{
+		"SYNC_LOGGING=yes please",
+		"MAX_IMPORT_TASKS=-1",
+		"LOG_THROTTLE_INTERVAL=soon",
+		"LOG_FORMAT=xml",
+		"VALIDATION_ERROR_STATUS=500",
+	}
+	_, _, err := LoadConfig(append([]string{"ENABLE_UI=true"}, bad...))
+	if err == nil {
+		t.Fatal("LoadConfig accepted invalid values")
+	}
+	for _, entry := range bad {
+		name, _, _ := strings.Cut(entry, "=")
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error does not mention %s:\n%v", name, err)
+		}
+	}
+	if strings.Contains(err.Error(), "ENABLE_UI") {
+		t.Errorf("error mentions the valid ENABLE_UI:\n%v", err)
+	}
+	if lines := strings.Count(err.Error(), "\n") + 1; lines != len(bad) {
+		t.Errorf("error has %d lines, want one per bad value (%d):\n%v", lines, len(bad), err)
+	}
+}
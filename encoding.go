@@ -0,0 +1,39 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// Распаковка тела запроса по Content-Encoding. Поддерживается gzip;
+// размер распакованного тела ограничен maxBytes (MAX_DECODED_BODY_BYTES),
+// чтобы небольшой архив не развернулся в гигабайты. Запросы с телом
+// в другой кодировке отклоняются с 415; запросы без тела (GET, HEAD)
+// пропускаются независимо от заголовка.
+func WithRequestDecoding(next http.Handler, maxBytes int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+		case "", "identity":
+		case "gzip":
+			body, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, "Некорректное gzip-тело запроса", http.StatusBadRequest)
+				return
+			}
+			defer body.Close()
+			r.Body = http.MaxBytesReader(w, body, maxBytes)
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+		default:
+			http.Error(w, "Неподдерживаемая кодировка тела: "+encoding+" (поддерживается gzip)", http.StatusUnsupportedMediaType)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func gzipBody(t *testing.T, body string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(body)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestOversizedGzipBodyIs413OnEveryWriteEndpoint(t *testing.T) {
+	service := newTestService(t, "MAX_DECODED_BODY_BYTES=64")
+	router := NewRouter(NewTaskHandler(service), service.config)
+	service.store.Create(Task{Title: "t"})
+	service.store.Create(Task{Title: "u"})
+	padding := strings.Repeat(" ", 128)
+	tests := []struct {
+		method, target, body string
+	}{
+		{"POST", "/tasks", `{"title": "t"}`},
+		{"PATCH", "/tasks/1", `[{"op": "replace", "path": "/progress", "value": 5}]`},
+		{"POST", "/tasks/1/progress", `{"delta": 5}`},
+		{"POST", "/tasks/bulk-complete", `{"items": []}`},
+		{"POST", "/tasks/status", `{"ids": [1]}`},
+		{"PUT", "/tasks/1/order", `{"after": 2}`},
+		{"POST", "/tasks/import", `[]`},
+	}
+	for _, tt := range tests {
+		body := gzipBody(t, padding+tt.body)
+		header := []string{"Content-Encoding", "gzip"}
+		if tt.method == "PATCH" {
+			header = append(header, "Content-Type", "application/json-patch+json")
+		}
+		if w := serve(router, tt.method, tt.target, body, header...); w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s %s: status %d, want 413 (%s)", tt.method, tt.target, w.Code, strings.TrimSpace(w.Body.String()))
+		}
+		if w := serve(router, tt.method, tt.target, gzipBody(t, tt.body), header...); w.Code == http.StatusRequestEntityTooLarge {
+			t.Errorf("%s %s: small body rejected with 413", tt.method, tt.target)
+		}
+	}
+}
+
+func TestRequestContentEncoding(t *testing.T) {
+	service := newTestService(t)
+	router := NewRouter(NewTaskHandler(service), service.config)
+
+	w := serve(router, "POST", "/tasks", gzipBody(t, `{"title": "сжатая"}`), "Content-Encoding", "gzip")
+	var created Task
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if w.Code != http.StatusCreated || created.Title != "сжатая" {
+		t.Errorf("gzip body: status %d, title %q; want 201 and the decoded title (%s)", w.Code, created.Title, w.Body)
+	}
+	for _, encoding := range []string{"GZIP", " gzip "} {
+		if w := serve(router, "POST", "/tasks", gzipBody(t, `{"title": "t"}`), "Content-Encoding", encoding); w.Code != http.StatusCreated {
+			t.Errorf("Content-Encoding %q: status %d, want 201", encoding, w.Code)
+		}
+	}
+	if w := serve(router, "POST", "/tasks", `{"title": "t"}`, "Content-Encoding", "gzip"); w.Code != http.StatusBadRequest {
+		t.Errorf("plain body labelled gzip: status %d, want 400", w.Code)
+	}
+	if w := serve(router, "POST", "/tasks", `{"title": "t"}`, "Content-Encoding", "identity"); w.Code != http.StatusCreated {
+		t.Errorf("identity: status %d, want 201", w.Code)
+	}
+
+	for _, encoding := range []string{"br", "deflate", "gzip, br"} {
+		if w := serve(router, "POST", "/tasks", `{"title": "t"}`, "Content-Encoding", encoding); w.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("POST with Content-Encoding %q: status %d, want 415", encoding, w.Code)
+		}
+		// Без тела кодировка не важна
+		if w := serve(router, "GET", "/tasks", "", "Content-Encoding", encoding); w.Code != http.StatusOK {
+			t.Errorf("GET with Content-Encoding %q: status %d, want 200", encoding, w.Code)
+		}
+	}
+	if w := serve(router, "HEAD", "/tasks/1", "", "Content-Encoding", "br"); w.Code != http.StatusOK {
+		t.Errorf("HEAD with Content-Encoding br: status %d, want 200", w.Code)
+	}
+}
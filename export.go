@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// Количество задач между принудительными отправками буфера клиенту
+const exportFlushEvery = 100
+
+// Обработчик GET /tasks/export
+// Снимок задач берётся под блокировкой чтения, а JSON-массив пишется
+// клиенту по частям уже без блокировки: скобки, задачи и запятые
+// отправляются по мере кодирования (chunked transfer encoding).
+// С фильтрами списка выгрузка частичная и помечается заголовками
+// X-Export-Partial и X-Export-Filter. Размер выгрузки ограничен
+// MAX_EXPORT_TASKS: число задач известно до начала записи.
+func (h *TaskHandler) ExportTasks(w http.ResponseWriter, r *http.Request) {
+	filter := parseTaskFilter(r)
+	tasks := h.service.store.GetAll(filter)
+	slices.SortFunc(tasks, func(a, b Task) int { return a.ID - b.ID })
+	if limit := h.service.config.MaxExportTasks; limit > 0 && len(tasks) > limit {
+		h.service.LogWarn(r.Context(), "Экспорт задач отклонён: задач "+strconv.Itoa(len(tasks))+" при MAX_EXPORT_TASKS="+strconv.Itoa(limit))
+		http.Error(w, "Выгрузка превышает MAX_EXPORT_TASKS="+strconv.Itoa(limit)+", уточните фильтры", http.StatusInternalServerError)
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "application/json")
+	partial := filter != TaskFilter{}
+	if partial {
+		w.Header().Set("X-Export-Partial", "true")
+		w.Header().Set("X-Export-Filter", filter.String())
+	}
+	sent, err := 0, writeString(w, "[")
+	for i := 0; err == nil && i < len(tasks); i++ {
+		data, _ := json.Marshal(h.service.present(tasks[i]))
+		if i > 0 {
+			data = append([]byte(","), data...)
+		}
+		if _, err = w.Write(data); err != nil {
+			break
+		}
+		sent++
+		if flusher != nil && sent%exportFlushEvery == 0 {
+			flusher.Flush()
+		}
+	}
+	if err == nil {
+		err = writeString(w, "]\n")
+	}
+	// Асинхронное логирование
+	if err != nil {
+		h.service.LogWarn(r.Context(), "Экспорт задач прерван: отправлено "+strconv.Itoa(sent)+" из "+strconv.Itoa(len(tasks)))
+		return
+	}
+	if partial {
+		h.service.Log(r.Context(), "Частичный экспорт задач ("+filter.String()+"): выгружено "+strconv.Itoa(sent))
+		return
+	}
+	h.service.Log(r.Context(), "Экспорт задач: выгружено "+strconv.Itoa(sent))
+}
+
+// Запись строки в ответ
+func writeString(w http.ResponseWriter, s string) error {
+	_, err := w.Write([]byte(s))
+	return err
+}
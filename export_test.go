@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportManyTasksIsValidJSON(t *testing.T) {
+	service := newTestService(t)
+	router := NewRouter(NewTaskHandler(service), service.config)
+	// Больше нескольких порций exportFlushEvery, чтобы проверить стыки
+	const total = exportFlushEvery*3 + 7
+	for i := range total {
+		service.store.Create(Task{Title: "t" + strconv.Itoa(i)})
+	}
+
+	w := serve(router, "GET", "/tasks/export", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d (%s)", w.Code, w.Body)
+	}
+	var tasks []Task
+	if err := json.Unmarshal(w.Body.Bytes(), &tasks); err != nil {
+		t.Fatalf("export is not valid JSON: %v", err)
+	}
+	if len(tasks) != total {
+		t.Fatalf("exported %d tasks, want %d", len(tasks), total)
+	}
+	for i, task := range tasks {
+		if task.ID != i+1 {
+			t.Fatalf("task %d has ID %d, want tasks ordered by ID", i, task.ID)
+		}
+	}
+	if w.Header().Get("X-Export-Partial") != "" {
+		t.Error("full export is marked partial")
+	}
+}
+
+// Ответ, запись в который обрывается после limit байт
+type abortingWriter struct {
+	*httptest.ResponseRecorder
+	limit int
+}
+
+func (w *abortingWriter) Write(p []byte) (int, error) {
+	if w.Body.Len()+len(p) > w.limit {
+		return 0, errors.New("connection reset by peer")
+	}
+	return w.ResponseRecorder.Write(p)
+}
+
+func TestExportAbortedWrite(t *testing.T) {
+	buf := bufferLogSink(t)
+	service := newTestService(t, "SYNC_LOGGING=true")
+	router := NewRouter(NewTaskHandler(service), service.config)
+	for i := range 50 {
+		service.store.Create(Task{Title: "t" + strconv.Itoa(i)})
+	}
+
+	w := &abortingWriter{ResponseRecorder: httptest.NewRecorder(), limit: 1000}
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/tasks/export", nil))
+
+	var tasks []Task
+	if json.Unmarshal(w.Body.Bytes(), &tasks) == nil {
+		t.Error("aborted export decoded as a complete array")
+	}
+	// Отправляются только целые задачи, и их число попадает в лог
+	sent := strings.Count(w.Body.String(), `"id":`)
+	want := "Экспорт задач прерван: отправлено " + strconv.Itoa(sent) + " из 50"
+	if sent == 0 || !strings.Contains(buf.String(), "WARN "+want) {
+		t.Errorf("log %q, want warning %q", buf.String(), want)
+	}
+	if strings.Contains(buf.String(), "Экспорт задач: выгружено") {
+		t.Error("aborted export logged as successful")
+	}
+}
+
+func TestFilteredExportHeaders(t *testing.T) {
+	buf := bufferLogSink(t)
+	service := newTestService(t, "SYNC_LOGGING=true")
+	router := NewRouter(NewTaskHandler(service), service.config)
+	due := time.Now().Add(time.Hour)
+	service.store.Create(Task{Title: "a"})
+	service.store.Create(Task{Title: "b", DueDate: &due})
+	service.store.Create(Task{Title: "c"})
+	service.store.Update(3, func(task *Task) error {
+		task.Completed = true
+		return nil
+	})
+
+	tests := []struct {
+		query, filter string
+		ids           []int
+	}{
+		{"completed=false", "completed=false; has_due_date=any", []int{1, 2}},
+		{"has_due_date=true", "completed=any; has_due_date=true", []int{2}},
+		{"completed=true&has_due_date=true", "completed=true; has_due_date=true", []int{}},
+	}
+	for _, tt := range tests {
+		w := serve(router, "GET", "/tasks/export?"+tt.query, "")
+		if w.Header().Get("X-Export-Partial") != "true" || w.Header().Get("X-Export-Filter") != tt.filter {
+			t.Errorf("%s: X-Export-Partial %q, X-Export-Filter %q; want true, %q",
+				tt.query, w.Header().Get("X-Export-Partial"), w.Header().Get("X-Export-Filter"), tt.filter)
+		}
+		if ids := listIDs(t, router, "/tasks/export?"+tt.query); !slices.Equal(ids, tt.ids) {
+			t.Errorf("%s: exported IDs %v, want %v", tt.query, ids, tt.ids)
+		}
+		if want := "Частичный экспорт задач (" + tt.filter + "): выгружено " + strconv.Itoa(len(tt.ids)); !strings.Contains(buf.String(), want) {
+			t.Errorf("%s: log does not contain %q", tt.query, want)
+		}
+	}
+	// Нераспознанное значение фильтра не делает выгрузку частичной
+	w := serve(router, "GET", "/tasks/export?completed=maybe", "")
+	if w.Header().Get("X-Export-Partial") != "" || w.Header().Get("X-Export-Filter") != "" {
+		t.Errorf("unrecognized filter marked the export partial: %v", w.Header())
+	}
+}
+
+func TestMaxExportTasks(t *testing.T) {
+	buf := bufferLogSink(t)
+	service := newTestService(t, "MAX_EXPORT_TASKS=3", "SYNC_LOGGING=true")
+	router := NewRouter(NewTaskHandler(service), service.config)
+	for range 5 {
+		service.store.Create(Task{Title: "t"})
+	}
+	for _, id := range []int{1, 2} {
+		service.store.Update(id, func(task *Task) error {
+			task.Completed = true
+			return nil
+		})
+	}
+
+	// Лимит проверяется до начала потоковой записи: ни одной задачи в ответе
+	w := serve(router, "GET", "/tasks/export", "")
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), `"id"`) {
+		t.Errorf("5 tasks over MAX_EXPORT_TASKS=3: status %d (%s); want 500 without tasks", w.Code, w.Body)
+	}
+	if !strings.Contains(buf.String(), "WARN Экспорт задач отклонён: задач 5 при MAX_EXPORT_TASKS=3") {
+		t.Errorf("log %q, want the rejection warning", buf.String())
+	}
+	// Фильтр, сокращающий выгрузку до лимита, проходит
+	if ids := listIDs(t, router, "/tasks/export?completed=false"); !slices.Equal(ids, []int{3, 4, 5}) {
+		t.Errorf("filtered export IDs %v, want [3 4 5]", ids)
+	}
+}
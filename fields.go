@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// Имена полей задачи в JSON
+var taskFieldNames = func() map[string]bool {
+	names := map[string]bool{}
+	t := reflect.TypeOf(Task{})
+	for i := 0; i < t.NumField(); i++ {
+		if name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ","); name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}()
+
+// Список полей из параметра fields; nil — выдавать задачу целиком.
+// Поле id включается всегда.
+func parseFields(r *http.Request) ([]string, error) {
+	param := r.URL.Query().Get("fields")
+	if param == "" {
+		return nil, nil
+	}
+	fields := []string{"id"}
+	for _, field := range strings.Split(param, ",") {
+		field = strings.TrimSpace(field)
+		if !taskFieldNames[field] {
+			return nil, fmt.Errorf("неизвестное поле %q", field)
+		}
+		if field != "id" {
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// Задача, сокращённая до указанных полей
+func projectTask(task Task, fields []string) any {
+	if fields == nil {
+		return task
+	}
+	data, _ := json.Marshal(task)
+	var all map[string]json.RawMessage
+	json.Unmarshal(data, &all)
+	projected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			projected[field] = value
+		}
+	}
+	return projected
+}
+
+// Список задач, сокращённых до указанных полей
+func projectTasks(tasks []Task, fields []string) any {
+	if fields == nil {
+		return tasks
+	}
+	projected := make([]any, len(tasks))
+	for i, task := range tasks {
+		projected[i] = projectTask(task, fields)
+	}
+	return projected
+}
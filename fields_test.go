@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Ключи JSON-объекта в алфавитном порядке
+func objectKeys(t *testing.T, data []byte) []string {
+	t.Helper()
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		t.Fatalf("%s: %v", data, err)
+	}
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func TestFieldsProjection(t *testing.T) {
+	service := newTestService(t)
+	router := NewRouter(NewTaskHandler(service), service.config)
+	service.store.Create(Task{Title: "a", Progress: 40})
+
+	tests := []struct {
+		fields string
+		want   []string
+	}{
+		{"title", []string{"id", "title"}},
+		{"progress, completed", []string{"completed", "id", "progress"}},
+		{"id", []string{"id"}},                      // id не дублируется
+		{"title,due_date", []string{"id", "title"}}, // Отсутствующее необязательное поле пропускается
+	}
+	for _, tt := range tests {
+		w := serve(router, "GET", "/tasks/1?fields="+strings.ReplaceAll(tt.fields, " ", "%20"), "")
+		if got := objectKeys(t, w.Body.Bytes()); !slices.Equal(got, tt.want) {
+			t.Errorf("GET /tasks/1 fields=%s: keys %v, want %v", tt.fields, got, tt.want)
+		}
+		w = serve(router, "GET", "/tasks?fields="+strings.ReplaceAll(tt.fields, " ", "%20"), "")
+		var list []json.RawMessage
+		if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list) != 1 {
+			t.Fatalf("GET /tasks fields=%s: %s", tt.fields, w.Body)
+		}
+		if got := objectKeys(t, list[0]); !slices.Equal(got, tt.want) {
+			t.Errorf("GET /tasks fields=%s: keys %v, want %v", tt.fields, got, tt.want)
+		}
+	}
+
+	w := serve(router, "GET", "/tasks/1?fields=title", "")
+	if body := strings.TrimSpace(w.Body.String()); body != `{"id":1,"title":"a"}` {
+		t.Errorf("projected body = %s", body)
+	}
+	// Без fields задача выдаётся целиком
+	if got := objectKeys(t, serve(router, "GET", "/tasks/1", "").Body.Bytes()); len(got) != len(taskFieldNames)-2 {
+		t.Errorf("full task keys %v, want all fields except the omitted due_date and completed_at", got)
+	}
+
+	for _, target := range []string{"/tasks/1?fields=owner", "/tasks?fields=title,owner", "/tasks?fields=title,", "/tasks?fields=ChangeSeq"} {
+		if w := serve(router, "GET", target, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: status %d, want 400", target, w.Code)
+		}
+	}
+}
+
+func TestIDsOnly(t *testing.T) {
+	service := newTestService(t)
+	router := NewRouter(NewTaskHandler(service), service.config)
+	for _, title := range []string{"b", "c", "a"} {
+		service.store.Create(Task{Title: title})
+	}
+	service.store.Update(2, func(task *Task) error {
+		task.Completed = true
+		return nil
+	})
+
+	tests := map[string]string{
+		"/tasks?ids_only=true":                        "[1,2,3]",
+		"/tasks?ids_only=true&sort=title":             "[3,1,2]",
+		"/tasks?ids_only=true&completed=false":        "[1,3]",
+		"/tasks?ids_only=true&completed=true&fields=": "[2]", // Пустой fields не задан
+		"/tasks?ids_only=false&fields=title":          `[{"id":1,"title":"b"},{"id":2,"title":"c"},{"id":3,"title":"a"}]`,
+		"/tasks?ids_only=maybe&fields=title":          `[{"id":1,"title":"b"},{"id":2,"title":"c"},{"id":3,"title":"a"}]`,
+	}
+	for target, want := range tests {
+		w := serve(router, "GET", target, "")
+		if got := strings.TrimSpace(w.Body.String()); w.Code != http.StatusOK || got != want {
+			t.Errorf("GET %s: status %d, body %s; want %s", target, w.Code, got, want)
+		}
+	}
+	for _, target := range []string{"/tasks?ids_only=true&fields=title", "/tasks?ids_only=1&fields=id"} {
+		if w := serve(router, "GET", target, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: status %d, want 400", target, w.Code)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Фильтр списка задач; nil-поля не ограничивают выборку
+type TaskFilter struct {
+	Completed    *bool  // Статус выполнения
+	HasDueDate   *bool  // Наличие срока выполнения
+	ChangedSince uint64 // Только задачи, изменённые после этого номера (0 — все)
+}
+
+// Соответствие задачи фильтру
+func (f TaskFilter) Matches(task Task) bool {
+	if f.Completed != nil && task.Completed != *f.Completed {
+		return false
+	}
+	if f.HasDueDate != nil && (task.DueDate != nil) != *f.HasDueDate {
+		return false
+	}
+	if task.ChangeSeq <= f.ChangedSince {
+		return false
+	}
+	return true
+}
+
+// Описание применённого фильтра вида "completed=true; has_due_date=any",
+// где any означает, что параметр не задан или не распознан;
+// since добавляется, только если задан
+func (f TaskFilter) String() string {
+	description := "completed=" + formatBoolFilter(f.Completed) + "; has_due_date=" + formatBoolFilter(f.HasDueDate)
+	if f.ChangedSince > 0 {
+		description += "; since=" + strconv.FormatUint(f.ChangedSince, 10)
+	}
+	return description
+}
+
+func formatBoolFilter(value *bool) string {
+	if value == nil {
+		return "any"
+	}
+	return strconv.FormatBool(*value)
+}
+
+// Задачи, соответствующие фильтру
+func filterTasks(tasks []Task, filter TaskFilter) []Task {
+	result := []Task{}
+	for _, task := range tasks {
+		if filter.Matches(task) {
+			result = append(result, task)
+		}
+	}
+	return result
+}
+
+// Фильтр из параметров запроса
+func parseTaskFilter(r *http.Request) TaskFilter {
+	// Некорректное значение since, как и у логических параметров, игнорируется
+	since, _ := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+	return TaskFilter{
+		Completed:    parseBoolParam(r, "completed"),
+		HasDueDate:   parseBoolParam(r, "has_due_date"),
+		ChangedSince: since,
+	}
+}
+
+// Логический параметр запроса; некорректное значение игнорируется
+func parseBoolParam(r *http.Request, name string) *bool {
+	if param := r.URL.Query().Get(name); param != "" {
+		val, err := strconv.ParseBool(param)
+		if err == nil {
+			return &val
+		}
+	}
+	return nil
+}
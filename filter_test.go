@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// ID задач из ответа GET /tasks
+func listIDs(t *testing.T, handler http.Handler, target string) []int {
+	t.Helper()
+	w := serve(handler, "GET", target, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: status %d (%s)", target, w.Code, w.Body)
+	}
+	var tasks []Task
+	if err := json.Unmarshal(w.Body.Bytes(), &tasks); err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]int, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	return ids
+}
+
+func TestHasDueDateFilter(t *testing.T) {
+	for model := range storageModels {
+		t.Run(model, func(t *testing.T) {
+			service := newTestService(t, "STORAGE_MODEL="+model)
+			router := NewRouter(NewTaskHandler(service), service.config)
+			due := time.Now().Add(24 * time.Hour)
+			service.store.Create(Task{Title: "a", DueDate: &due})
+			service.store.Create(Task{Title: "b"})
+			service.store.Create(Task{Title: "c", DueDate: &due})
+
+			tests := map[string][]int{
+				"/tasks?has_due_date=true":                 {1, 3},
+				"/tasks?has_due_date=false":                {2},
+				"/tasks?has_due_date=maybe":                {1, 2, 3}, // Некорректное значение игнорируется
+				"/tasks?has_due_date=true&completed=false": {1, 3},
+				"/tasks?has_due_date=false&completed=true": {},
+				"/tasks/export?has_due_date=false":         {2},
+			}
+			for target, want := range tests {
+				if got := listIDs(t, router, target); !slices.Equal(got, want) {
+					t.Errorf("GET %s: IDs %v, want %v", target, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAppliedFiltersHeader(t *testing.T) {
+	service := newTestService(t, "APPLIED_FILTERS_HEADER=true")
+	router := NewRouter(NewTaskHandler(service), service.config)
+	tests := map[string]string{
+		"/tasks":                                 "completed=any; has_due_date=any; sort=id; order=asc",
+		"/tasks?completed=false&has_due_date=1":  "completed=false; has_due_date=true; sort=id; order=asc",
+		"/tasks?completed=maybe&sort=created_at": "completed=any; has_due_date=any; sort=created_at; order=desc",
+		"/tasks?sort=title&order=desc&since=5":   "completed=any; has_due_date=any; since=5; sort=title; order=desc",
+	}
+	for target, want := range tests {
+		if got := serve(router, "GET", target, "").Header().Get("X-Applied-Filters"); got != want {
+			t.Errorf("GET %s: X-Applied-Filters %q, want %q", target, got, want)
+		}
+	}
+	// Некорректная сортировка отклоняется до формирования заголовка
+	if w := serve(router, "GET", "/tasks?sort=owner", ""); w.Header().Get("X-Applied-Filters") != "" {
+		t.Errorf("rejected request has X-Applied-Filters %q", w.Header().Get("X-Applied-Filters"))
+	}
+
+	disabled := newTestService(t)
+	if got := serve(NewRouter(NewTaskHandler(disabled), disabled.config), "GET", "/tasks", "").Header().Get("X-Applied-Filters"); got != "" {
+		t.Errorf("without APPLIED_FILTERS_HEADER: X-Applied-Filters %q", got)
+	}
+}
+
+func TestChangeSequenceAndSince(t *testing.T) {
+	for model := range storageModels {
+		t.Run(model, func(t *testing.T) {
+			service := newTestService(t, "STORAGE_MODEL="+model)
+			router := NewRouter(NewTaskHandler(service), service.config)
+			sequence := func(target string) uint64 {
+				t.Helper()
+				value, err := strconv.ParseUint(serve(router, "GET", target, "").Header().Get(changeSequenceHeader), 10, 64)
+				if err != nil {
+					t.Fatalf("GET %s: %s: %v", target, changeSequenceHeader, err)
+				}
+				return value
+			}
+
+			start := sequence("/tasks")
+			service.store.Create(Task{Title: "a"})
+			service.store.Create(Task{Title: "b"})
+			afterCreate := sequence("/tasks")
+			if afterCreate != start+2 {
+				t.Errorf("sequence after 2 creates = %d, want %d", afterCreate, start+2)
+			}
+			if got := sequence("/tasks/1"); got != afterCreate {
+				t.Errorf("GET /tasks/1 sequence = %d, want %d", got, afterCreate)
+			}
+			// Чтение номер не увеличивает
+			if got := sequence("/tasks"); got != afterCreate {
+				t.Errorf("sequence after reads = %d, want %d", got, afterCreate)
+			}
+
+			service.store.Update(1, func(task *Task) error {
+				task.Title = "a2"
+				return nil
+			})
+			afterUpdate := sequence("/tasks")
+			if afterUpdate != afterCreate+1 {
+				t.Errorf("sequence after update = %d, want %d", afterUpdate, afterCreate+1)
+			}
+
+			since := "/tasks?since="
+			for target, want := range map[string][]int{
+				since + strconv.FormatUint(start, 10):       {1, 2},
+				since + strconv.FormatUint(afterCreate, 10): {1},
+				since + strconv.FormatUint(afterUpdate, 10): {},
+				since + "bogus": {1, 2}, // Некорректное значение игнорируется
+			} {
+				if ids := listIDs(t, router, target); !slices.Equal(ids, want) {
+					t.Errorf("GET %s: IDs %v, want %v", target, ids, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+module github.com/denagava/REST-API-in-Go-for-managing-Task-entities-with-asynchronous-logging-of-actions-via-a-channel
+
+go 1.22
+
+require golang.org/x/text v0.22.0
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// Хранилище, недоступность которого проверяется через Ping
+type unreachableStorage struct {
+	*TaskStorage
+}
+
+func (unreachableStorage) Ping(ctx context.Context) error {
+	return errors.New("хранилище недоступно")
+}
+
+func TestHealth(t *testing.T) {
+	tests := []struct {
+		name   string
+		store  Storage
+		target string
+		code   int
+		status string
+	}{
+		{"healthy deep", NewTaskStorage(), "/healthz?deep=true", http.StatusOK, "ok"},
+		{"failing deep", unreachableStorage{NewTaskStorage()}, "/healthz?deep=true", http.StatusServiceUnavailable, "unavailable"},
+		{"failing shallow", unreachableStorage{NewTaskStorage()}, "/healthz", http.StatusOK, "ok"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService(t)
+			service.store = tt.store
+			w := serve(NewRouter(NewTaskHandler(service), service.config), "GET", tt.target, "")
+			var body map[string]string
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != tt.code || body["status"] != tt.status {
+				t.Errorf("GET %s: %d %v, want %d status %q", tt.target, w.Code, body, tt.code, tt.status)
+			}
+			if tt.code == http.StatusServiceUnavailable && body["error"] == "" {
+				t.Error("503 response lacks the Ping error")
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Ошибки ключей идемпотентности
+var (
+	ErrIdempotencyInProgress = errors.New("запрос с таким Idempotency-Key уже выполняется")
+	ErrIdempotencyMismatch   = errors.New("Idempotency-Key уже использован с другим телом запроса")
+)
+
+// Хранилище результатов по ключам идемпотентности. Ключ хранится
+// IDEMPOTENCY_TTL с момента первого запроса; при превышении
+// IDEMPOTENCY_MAX_KEYS вытесняются самые старые ключи.
+type IdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List       // Ключи в порядке первого запроса, от старых к новым
+	wait    bool             // Ждать завершения параллельного запроса вместо отказа
+	ttl     time.Duration    // Время хранения ключа
+	maxKeys int              // Максимальное число хранимых ключей
+	now     func() time.Time // Источник текущего времени
+}
+
+// Запись о запросе: done закрывается, когда результат готов
+type idempotencyEntry struct {
+	key         string
+	fingerprint [sha256.Size]byte // Хеш тела первого запроса
+	created     time.Time
+	done        chan struct{}
+	task        Task
+}
+
+// Конструктор хранилища ключей идемпотентности
+func NewIdempotencyStore(wait bool, ttl time.Duration, maxKeys int) *IdempotencyStore {
+	return &IdempotencyStore{
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		wait:    wait,
+		ttl:     ttl,
+		maxKeys: maxKeys,
+		now:     time.Now,
+	}
+}
+
+// Отпечаток тела запроса для сравнения повторов с тем же ключом
+func requestFingerprint(v any) [sha256.Size]byte {
+	data, _ := json.Marshal(v)
+	return sha256.Sum256(data)
+}
+
+// Выполнение fn не более одного раза для ключа.
+// Первый запрос помечает ключ как выполняющийся и вызывает fn,
+// остальные ждут его результата (или получают ErrIdempotencyInProgress,
+// если ожидание отключено). Повтор с другим отпечатком тела получает
+// ErrIdempotencyMismatch. replayed сообщает, что результат повторный.
+func (s *IdempotencyStore) Do(ctx context.Context, key string, fingerprint [sha256.Size]byte, fn func() Task) (task Task, replayed bool, err error) {
+	s.mu.Lock()
+	s.prune()
+	element, exists := s.entries[key]
+	if !exists {
+		entry := &idempotencyEntry{key: key, fingerprint: fingerprint, created: s.now(), done: make(chan struct{})}
+		s.entries[key] = s.order.PushBack(entry)
+		for s.order.Len() > s.maxKeys {
+			s.remove(s.order.Front())
+		}
+		s.mu.Unlock()
+
+		entry.task = fn()
+		close(entry.done)
+		return entry.task, false, nil
+	}
+	entry := element.Value.(*idempotencyEntry)
+	s.mu.Unlock()
+
+	if entry.fingerprint != fingerprint {
+		return Task{}, false, ErrIdempotencyMismatch
+	}
+	select {
+	case <-entry.done:
+		return entry.task, true, nil
+	default:
+	}
+	if !s.wait {
+		return Task{}, false, ErrIdempotencyInProgress
+	}
+	select {
+	case <-entry.done:
+		return entry.task, true, nil
+	case <-ctx.Done():
+		return Task{}, false, ctx.Err()
+	}
+}
+
+// Удаление ключей старше ttl; ключи упорядочены по времени создания,
+// поэтому проверка останавливается на первом неистёкшем
+func (s *IdempotencyStore) prune() {
+	for element := s.order.Front(); element != nil; element = s.order.Front() {
+		if s.now().Sub(element.Value.(*idempotencyEntry).created) < s.ttl {
+			return
+		}
+		s.remove(element)
+	}
+}
+
+func (s *IdempotencyStore) remove(element *list.Element) {
+	delete(s.entries, element.Value.(*idempotencyEntry).key)
+	s.order.Remove(element)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyConcurrentSameKey(t *testing.T) {
+	store := NewIdempotencyStore(true, time.Hour, 100)
+	fingerprint := requestFingerprint(Task{Title: "a"})
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+	results := make([]Task, 50)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			task, _, err := store.Do(context.Background(), "key", fingerprint, func() Task {
+				calls.Add(1)
+				time.Sleep(time.Millisecond)
+				return Task{ID: 1, Title: "a"}
+			})
+			if err != nil {
+				t.Errorf("Do: %v", err)
+			}
+			results[i] = task
+		}()
+	}
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Errorf("fn called %d times, want 1", calls.Load())
+	}
+	for i, task := range results {
+		if task.ID != 1 {
+			t.Errorf("result %d: ID = %d, want 1", i, task.ID)
+		}
+	}
+}
+
+func TestIdempotencyConcurrentSameKeyReject(t *testing.T) {
+	store := NewIdempotencyStore(false, time.Hour, 100)
+	fingerprint := requestFingerprint(Task{Title: "a"})
+	started, release := make(chan struct{}), make(chan struct{})
+	go store.Do(context.Background(), "key", fingerprint, func() Task {
+		close(started)
+		<-release
+		return Task{ID: 1}
+	})
+	<-started
+	if _, _, err := store.Do(context.Background(), "key", fingerprint, func() Task { return Task{} }); !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Errorf("err = %v, want ErrIdempotencyInProgress", err)
+	}
+	close(release)
+}
+
+func TestIdempotencyBodyMismatch(t *testing.T) {
+	store := NewIdempotencyStore(true, time.Hour, 100)
+	store.Do(context.Background(), "key", requestFingerprint(Task{Title: "a"}), func() Task { return Task{ID: 1} })
+	_, _, err := store.Do(context.Background(), "key", requestFingerprint(Task{Title: "DIFFERENT"}), func() Task { return Task{ID: 2} })
+	if !errors.Is(err, ErrIdempotencyMismatch) {
+		t.Errorf("err = %v, want ErrIdempotencyMismatch", err)
+	}
+}
+
+func TestIdempotencyExpiryAndCap(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewIdempotencyStore(true, time.Minute, 2)
+	store.now = func() time.Time { return now }
+	do := func(key string, id int) (Task, bool) {
+		task, replayed, _ := store.Do(context.Background(), key, requestFingerprint(key), func() Task { return Task{ID: id} })
+		return task, replayed
+	}
+
+	do("a", 1)
+	if _, replayed := do("a", 2); !replayed {
+		t.Error("key a not replayed before expiry")
+	}
+	now = now.Add(time.Minute)
+	if task, replayed := do("a", 3); replayed || task.ID != 3 {
+		t.Errorf("expired key a: task %d, replayed %v", task.ID, replayed)
+	}
+
+	do("b", 4)
+	do("c", 5)
+	if store.order.Len() != 2 {
+		t.Errorf("stored keys = %d, want 2", store.order.Len())
+	}
+	if _, replayed := do("a", 6); replayed {
+		t.Error("oldest key a was not evicted")
+	}
+}
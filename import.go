@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// Размер пачки задач, сохраняемой под одной блокировкой
+const importBatchSize = 100
+
+// Ошибки импорта
+var (
+	ErrImportInvalid  = errors.New("некорректные данные импорта")
+	ErrImportTooLarge = errors.New("превышено максимальное количество задач в импорте")
+	ErrImportBusy     = errors.New("уже выполняется другой импорт")
+)
+
+// Политика для импортируемой задачи, ID которой уже занят
+type ConflictPolicy string
+
+const (
+	ConflictSkip      ConflictPolicy = "skip"      // Оставить существующую задачу
+	ConflictOverwrite ConflictPolicy = "overwrite" // Заменить существующую задачу
+	ConflictReassign  ConflictPolicy = "reassign"  // Сохранить под новым ID
+)
+
+// Разбор политики конфликтов, по умолчанию skip
+func ParseConflictPolicy(value string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(value); policy {
+	case "":
+		return ConflictSkip, nil
+	case ConflictSkip, ConflictOverwrite, ConflictReassign:
+		return policy, nil
+	}
+	return "", fmt.Errorf("%w: неизвестная политика конфликтов %q", ErrImportInvalid, value)
+}
+
+// Счётчики импорта
+type ImportCounts struct {
+	Imported    int `json:"imported"`    // Сохранено задач
+	Skipped     int `json:"skipped"`     // Пропущено из-за занятого ID
+	Overwritten int `json:"overwritten"` // Заменено существующих задач
+	Reassigned  int `json:"reassigned"`  // Сохранено под новым ID
+}
+
+// Добавление счётчиков очередной пачки
+func (c *ImportCounts) Add(other ImportCounts) {
+	c.Imported += other.Imported
+	c.Skipped += other.Skipped
+	c.Overwritten += other.Overwritten
+	c.Reassigned += other.Reassigned
+}
+
+// Итог импорта
+type ImportSummary struct {
+	ImportCounts
+	Error string `json:"error,omitempty"` // Причина остановки импорта
+}
+
+// Потоковый импорт JSON-массива задач: элементы декодируются по одному
+// и сохраняются пачками, поэтому массив целиком в памяти не хранится.
+// При ошибке уже сохранённые пачки остаются в хранилище.
+// Импорты выполняются по одному, чтобы пачки параллельных импортов
+// не чередовались; второй импорт ждёт или, при IMPORT_CONCURRENT=reject,
+// сразу получает ErrImportBusy. Обычные операции с задачами не блокируются.
+func (s *TaskService) Import(r io.Reader, policy ConflictPolicy) (ImportSummary, error) {
+	var summary ImportSummary
+	if s.config.ImportWait {
+		s.importMu.Lock()
+	} else if !s.importMu.TryLock() {
+		return summary, ErrImportBusy
+	}
+	defer s.importMu.Unlock()
+	dec := json.NewDecoder(r)
+	if token, err := dec.Token(); err != nil {
+		return summary, fmt.Errorf("%w: ожидается JSON-массив: %w", ErrImportInvalid, err)
+	} else if token != json.Delim('[') {
+		return summary, fmt.Errorf("%w: ожидается JSON-массив", ErrImportInvalid)
+	}
+
+	batch := make([]Task, 0, importBatchSize)
+	flush := func() {
+		summary.Add(s.store.Import(batch, policy))
+		batch = batch[:0]
+	}
+	for count := 0; dec.More(); count++ {
+		if count >= s.config.MaxImportTasks {
+			flush()
+			return summary, ErrImportTooLarge
+		}
+		var task Task
+		if err := dec.Decode(&task); err != nil {
+			flush()
+			return summary, fmt.Errorf("%w: задача #%d: %w", ErrImportInvalid, count+1, err)
+		}
+		s.normalizeTitle(&task)
+		if errs := s.validator.ValidateImport(task); len(errs) > 0 {
+			flush()
+			return summary, fmt.Errorf("%w: задача #%d: %w", ErrImportInvalid, count+1, errs)
+		}
+		batch = append(batch, task)
+		if len(batch) == importBatchSize {
+			flush()
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		flush()
+		return summary, fmt.Errorf("%w: %w", ErrImportInvalid, err)
+	}
+	if _, err := dec.Token(); s.config.StrictJSON && err != io.EOF {
+		flush()
+		return summary, fmt.Errorf("%w: %w", ErrImportInvalid, ErrTrailingData)
+	}
+	flush()
+	return summary, nil
+}
+
+// Загрузка начальных задач из JSON-файла (SEED_FILE).
+// Выполняется только для пустого хранилища, чтобы не затереть
+// существующие данные; возвращает количество загруженных задач.
+func (s *TaskService) Seed(path string) (int, error) {
+	if s.store.Count(nil) > 0 {
+		return 0, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	summary, err := s.Import(file, ConflictSkip)
+	return summary.Imported, err
+}
+
+// Обработчик POST /tasks/import
+func (h *TaskHandler) ImportTasks(w http.ResponseWriter, r *http.Request) {
+	policy, err := ParseConflictPolicy(r.URL.Query().Get("on_conflict"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	summary, err := h.service.Import(r.Body, policy)
+	// Асинхронное логирование
+	h.service.Log(r.Context(), "Импорт задач: сохранено "+strconv.Itoa(summary.Imported))
+	// Формирование ответа
+	code := http.StatusOK
+	var validationErrs ValidationErrors
+	var bodyTooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &validationErrs):
+		code = h.service.config.ValidationStatus
+	case errors.Is(err, ErrImportTooLarge), errors.As(err, &bodyTooLarge):
+		code = http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrImportBusy):
+		code = http.StatusConflict
+	case err != nil:
+		code = http.StatusBadRequest
+	}
+	if err != nil {
+		summary.Error = err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(summary)
+}
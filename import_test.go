@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestImportOverwriteKeepsVersionMonotonic(t *testing.T) {
+	store := NewTaskStorage()
+	task := store.Create(Task{Title: "a"})
+	for range 2 {
+		store.Update(task.ID, func(task *Task) error { return nil })
+	}
+	if got, _ := store.GetByID(task.ID); got.Version != 3 {
+		t.Fatalf("version before import = %d, want 3", got.Version)
+	}
+
+	store.Import([]Task{{ID: task.ID, Title: "imported"}}, ConflictOverwrite)
+	got, _ := store.GetByID(task.ID)
+	if got.Version != 4 {
+		t.Errorf("version after overwrite = %d, want 4", got.Version)
+	}
+	results := store.CompleteBatch([]VersionedID{{ID: task.ID, Version: 1}})
+	if results[0].Status != BulkVersionMismatch {
+		t.Errorf("stale bulk-complete status = %q, want %q", results[0].Status, BulkVersionMismatch)
+	}
+}
+
+func TestImportNewTaskStartsAtVersionOne(t *testing.T) {
+	store := NewTaskStorage()
+	store.Import([]Task{{ID: 5, Title: "a", CreatedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}}, ConflictOverwrite)
+	if got, _ := store.GetByID(5); got.Version != 1 {
+		t.Errorf("version = %d, want 1", got.Version)
+	}
+}
+
+func TestImportRejectsHugeIDs(t *testing.T) {
+	service := newTestService(t)
+	for _, body := range []string{
+		`[{"id":9223372036854775807,"title":"x"}]`,
+		`[{"id":2147483648,"title":"x"}]`,
+	} {
+		summary, err := service.Import(strings.NewReader(body), ConflictSkip)
+		var errs ValidationErrors
+		if !errors.As(err, &errs) || summary.Imported != 0 {
+			t.Errorf("%s: imported %d, err = %v, want validation error", body, summary.Imported, err)
+		}
+	}
+	if created := service.store.Create(Task{Title: "next"}); created.ID != 1 {
+		t.Errorf("next created ID = %d, want 1", created.ID)
+	}
+
+	summary, err := service.Import(strings.NewReader(`[{"id":2147483647,"title":"x"}]`), ConflictSkip)
+	if err != nil || summary.Imported != 1 {
+		t.Fatalf("max ID: imported %d, err = %v", summary.Imported, err)
+	}
+	if created := service.store.Create(Task{Title: "next"}); created.ID <= 0 {
+		t.Errorf("ID after max import = %d, want positive", created.ID)
+	}
+}
+
+// JSON-массив из n задач; при withIDs у задач указаны ID 1..n
+func importBody(n int, withIDs bool) string {
+	parts := make([]string, n)
+	for i := range parts {
+		if withIDs {
+			parts[i] = `{"id":` + strconv.Itoa(i+1) + `,"title":"t"}`
+		} else {
+			parts[i] = `{"title":"t"}`
+		}
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+func TestConcurrentImportsWait(t *testing.T) {
+	for model := range storageModels {
+		t.Run(model, func(t *testing.T) {
+			service := newTestService(t, "STORAGE_MODEL="+model)
+			const imports, perImport = 8, 250
+			var wg sync.WaitGroup
+			for i := range imports {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					// Половина импортов конфликтует по ID и переназначает их
+					summary, err := service.Import(strings.NewReader(importBody(perImport, i%2 == 0)), ConflictReassign)
+					if err != nil || summary.Imported != perImport {
+						t.Errorf("import %d: imported %d, err = %v", i, summary.Imported, err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			tasks := service.store.GetAll(TaskFilter{})
+			if len(tasks) != imports*perImport {
+				t.Fatalf("tasks = %d, want %d", len(tasks), imports*perImport)
+			}
+			seen := map[int]bool{}
+			for _, task := range tasks {
+				if seen[task.ID] {
+					t.Errorf("duplicate ID %d", task.ID)
+				}
+				seen[task.ID] = true
+			}
+			if next := service.store.NextID(); next != imports*perImport+1 {
+				t.Errorf("NextID = %d, want %d", next, imports*perImport+1)
+			}
+		})
+	}
+}
+
+func TestConcurrentImportReject(t *testing.T) {
+	service := newTestService(t, "IMPORT_CONCURRENT=reject")
+	body, writer := io.Pipe()
+	first := make(chan error)
+	go func() {
+		_, err := service.Import(body, ConflictSkip)
+		first <- err
+	}()
+	// Первый импорт занят чтением тела, пока в pipe ничего не записано
+	writer.Write([]byte(`[{"title":"a"}`))
+	if _, err := service.Import(strings.NewReader(`[]`), ConflictSkip); !errors.Is(err, ErrImportBusy) {
+		t.Errorf("second import: err = %v, want ErrImportBusy", err)
+	}
+	writer.Write([]byte(`]`))
+	writer.Close()
+	if err := <-first; err != nil {
+		t.Errorf("first import: %v", err)
+	}
+	if _, err := service.Import(strings.NewReader(`[]`), ConflictSkip); err != nil {
+		t.Errorf("import after first finished: %v", err)
+	}
+}
+
+func TestImportLargeArrayIsStreamed(t *testing.T) {
+	const total = 50000
+	service := newTestService(t, "MAX_IMPORT_TASKS="+strconv.Itoa(total))
+	body, writer := io.Pipe()
+	// Пока вторая половина массива ещё не отправлена, первые пачки
+	// уже должны быть в хранилище: массив не читается в память целиком
+	storedMidway := make(chan int, 1)
+	go func() {
+		writer.Write([]byte("["))
+		for i := range total {
+			if i > 0 {
+				writer.Write([]byte(","))
+			}
+			if i == total/2 {
+				storedMidway <- service.store.Count(nil)
+			}
+			writer.Write([]byte(`{"title":"t` + strconv.Itoa(i) + `"}`))
+		}
+		writer.Write([]byte("]"))
+		writer.Close()
+	}()
+
+	summary, err := service.Import(body, ConflictSkip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Imported != total || service.store.Count(nil) != total {
+		t.Errorf("imported %d, stored %d, want %d", summary.Imported, service.store.Count(nil), total)
+	}
+	if midway := <-storedMidway; midway == 0 || midway > total/2 {
+		t.Errorf("stored %d tasks when half of the array was sent, want some but at most %d", midway, total/2)
+	}
+}
+
+func TestImportTooManyTasks(t *testing.T) {
+	service := newTestService(t, "MAX_IMPORT_TASKS=250")
+	router := NewRouter(NewTaskHandler(service), service.config)
+	w := serve(router, "POST", "/tasks/import", importBody(300, false))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status %d, want 413 (%s)", w.Code, w.Body)
+	}
+	var summary ImportSummary
+	if err := json.Unmarshal(w.Body.Bytes(), &summary); err != nil {
+		t.Fatal(err)
+	}
+	// Задачи до лимита уже сохранены пачками
+	if summary.Imported != 250 || service.store.Count(nil) != 250 || summary.Error == "" {
+		t.Errorf("summary %+v, stored %d, want 250 imported with an error", summary, service.store.Count(nil))
+	}
+}
+
+func TestImportConflictPolicies(t *testing.T) {
+	// В хранилище задачи 1 и 2; импортируются 2 (занят), 10 и задача без ID
+	const body = `[{"id":2,"title":"new 2"},{"id":10,"title":"new 10"},{"title":"no id"}]`
+	tests := []struct {
+		policy string
+		want   ImportCounts
+		titles map[int]string // Ожидаемые названия после импорта
+	}{
+		{"skip", ImportCounts{Imported: 2, Skipped: 1}, map[int]string{1: "old 1", 2: "old 2", 10: "new 10", 11: "no id"}},
+		{"overwrite", ImportCounts{Imported: 3, Overwritten: 1}, map[int]string{1: "old 1", 2: "new 2", 10: "new 10", 11: "no id"}},
+		{"reassign", ImportCounts{Imported: 3, Reassigned: 1}, map[int]string{1: "old 1", 2: "old 2", 3: "new 2", 10: "new 10", 11: "no id"}},
+	}
+	for _, tt := range tests {
+		for model := range storageModels {
+			t.Run(tt.policy+"/"+model, func(t *testing.T) {
+				service := newTestService(t, "STORAGE_MODEL="+model)
+				router := NewRouter(NewTaskHandler(service), service.config)
+				service.store.Create(Task{Title: "old 1"})
+				service.store.Create(Task{Title: "old 2"})
+
+				w := serve(router, "POST", "/tasks/import?on_conflict="+tt.policy, body)
+				if w.Code != http.StatusOK {
+					t.Fatalf("status %d (%s)", w.Code, w.Body)
+				}
+				var summary ImportSummary
+				if err := json.Unmarshal(w.Body.Bytes(), &summary); err != nil {
+					t.Fatal(err)
+				}
+				if summary.ImportCounts != tt.want {
+					t.Errorf("summary %+v, want %+v", summary.ImportCounts, tt.want)
+				}
+				if got := service.store.Count(nil); got != len(tt.titles) {
+					t.Errorf("stored %d tasks, want %d", got, len(tt.titles))
+				}
+				for id, title := range tt.titles {
+					if task, ok := service.store.GetByID(id); !ok || task.Title != title {
+						t.Errorf("task %d = %q (found %v), want %q", id, task.Title, ok, title)
+					}
+				}
+			})
+		}
+	}
+	service := newTestService(t)
+	router := NewRouter(NewTaskHandler(service), service.config)
+	if w := serve(router, "POST", "/tasks/import?on_conflict=merge", "[]"); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown policy: status %d, want 400", w.Code)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seed.json")
+	if err := os.WriteFile(path, []byte(`[{"title": "seed 1"}, {"id": 5, "title": "seed 5"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	service := newTestService(t)
+	seeded, err := service.Seed(path)
+	if err != nil || seeded != 2 {
+		t.Fatalf("Seed into an empty store = %d, %v; want 2", seeded, err)
+	}
+	for id, title := range map[int]string{1: "seed 1", 5: "seed 5"} {
+		if task, ok := service.store.GetByID(id); !ok || task.Title != title {
+			t.Errorf("task %d = %q (found %v), want %q", id, task.Title, ok, title)
+		}
+	}
+
+	// Непустое хранилище не трогается, даже если ID в файле свободны
+	service = newTestService(t)
+	service.store.Create(Task{Title: "existing"})
+	seeded, err = service.Seed(path)
+	if err != nil || seeded != 0 {
+		t.Errorf("Seed into a non-empty store = %d, %v; want 0", seeded, err)
+	}
+	if got := service.store.Count(nil); got != 1 {
+		t.Errorf("store has %d tasks after skipped seed, want 1", got)
+	}
+
+	if _, err := newTestService(t).Seed(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file: err = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestImportCreatedAt(t *testing.T) {
+	clock := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		createdAt string
+		ok        bool
+	}{
+		{"2019-03-04T05:06:07Z", true}, // Историческое время сохраняется как есть
+		{"1970-01-01T00:00:00Z", true},
+		{"2026-06-01T12:00:00Z", true},
+		{"2026-06-01T12:00:01Z", false},
+		{"2030-01-01T00:00:00Z", false},
+		{"1969-12-31T23:59:59Z", false},
+		{"0900-01-01T00:00:00Z", false},
+	}
+	for _, tt := range tests {
+		service := newTestService(t)
+		service.now = func() time.Time { return clock }
+		router := NewRouter(NewTaskHandler(service), service.config)
+		w := serve(router, "POST", "/tasks/import", `[{"id": 3, "title": "t", "created_at": "`+tt.createdAt+`"}]`)
+		task, stored := service.store.GetByID(3)
+		if !tt.ok {
+			if w.Code != http.StatusBadRequest || stored || !strings.Contains(w.Body.String(), "created_at") {
+				t.Errorf("created_at %s: status %d, stored %v (%s); want 400 naming created_at", tt.createdAt, w.Code, stored, w.Body)
+			}
+			continue
+		}
+		want, _ := time.Parse(time.RFC3339, tt.createdAt)
+		if w.Code != http.StatusOK || !task.CreatedAt.Equal(want) {
+			t.Errorf("created_at %s: status %d, stored %v (%s)", tt.createdAt, w.Code, task.CreatedAt, w.Body)
+		}
+	}
+
+	// Без created_at подставляется текущее время хранилища
+	service := newTestService(t)
+	service.store.(*TaskStorage).now = func() time.Time { return clock }
+	serve(NewRouter(NewTaskHandler(service), service.config), "POST", "/tasks/import", `[{"id": 3, "title": "t"}]`)
+	if task, _ := service.store.GetByID(3); !task.CreatedAt.Equal(clock) {
+		t.Errorf("missing created_at stored as %v, want %v", task.CreatedAt, clock)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestListIfModifiedSince(t *testing.T) {
+	service := newTestService(t)
+	router := NewRouter(NewTaskHandler(service), service.config)
+	store := service.store.(*TaskStorage)
+	base := time.Date(2026, 1, 2, 3, 4, 5, 200e6, time.UTC)
+	clock := base
+	store.now = func() time.Time { return clock }
+	service.now = func() time.Time { return clock }
+
+	store.Create(Task{Title: "a"})
+	first := serve(router, "GET", "/tasks", "")
+	lastModified := first.Header().Get("Last-Modified")
+	if lastModified != base.Format(http.TimeFormat) {
+		t.Fatalf("Last-Modified = %q, want %q", lastModified, base.Format(http.TimeFormat))
+	}
+
+	// Изменение в ту же секунду после чтения клиента
+	clock = base.Add(300 * time.Millisecond)
+	store.Create(Task{Title: "b"})
+	clock = base.Add(500 * time.Millisecond)
+	if w := serve(router, "GET", "/tasks", "", "If-Modified-Since", lastModified); w.Code != http.StatusOK {
+		t.Errorf("same-second change: status %d, want 200", w.Code)
+	}
+
+	clock = base.Add(2 * time.Second)
+	w := serve(router, "GET", "/tasks", "", "If-Modified-Since", lastModified)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("unchanged for 2s: status %d, want 304", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("304 body = %q, want empty", w.Body)
+	}
+
+	store.Create(Task{Title: "c"}) // Время изменения base+2s, уже после метки клиента
+	clock = base.Add(4 * time.Second)
+	if w := serve(router, "GET", "/tasks", "", "If-Modified-Since", lastModified); w.Code != http.StatusOK {
+		t.Errorf("changed after If-Modified-Since: status %d, want 200", w.Code)
+	}
+}
+
+func TestListIfNoneMatch(t *testing.T) {
+	service := newTestService(t)
+	router := NewRouter(NewTaskHandler(service), service.config)
+	service.store.Create(Task{Title: "a"})
+
+	etag := serve(router, "GET", "/tasks", "").Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag on the list")
+	}
+	for _, match := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		w := serve(router, "GET", "/tasks", "", "If-None-Match", match)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: status %d, body %q; want empty 304", match, w.Code, w.Body)
+		}
+	}
+	if w := serve(router, "GET", "/tasks", "", "If-None-Match", `"other"`); w.Code != http.StatusOK {
+		t.Errorf("mismatched ETag: status %d, want 200", w.Code)
+	}
+	// ETag зависит от параметров запроса
+	if w := serve(router, "GET", "/tasks?completed=true", "", "If-None-Match", etag); w.Code != http.StatusOK {
+		t.Errorf("ETag of another query: status %d, want 200", w.Code)
+	}
+	// If-None-Match важнее If-Modified-Since, даже если тот бы дал 304
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if w := serve(router, "GET", "/tasks", "", "If-None-Match", `"other"`, "If-Modified-Since", future); w.Code != http.StatusOK {
+		t.Errorf("mismatched ETag with If-Modified-Since: status %d, want 200", w.Code)
+	}
+
+	service.store.Create(Task{Title: "b"})
+	w := serve(router, "GET", "/tasks", "", "If-None-Match", etag)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("after a change: status %d, ETag %s; want 200 and a new ETag", w.Code, w.Header().Get("ETag"))
+	}
+}
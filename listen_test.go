@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestListenBusyAddress(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	addr := busy.Addr().String()
+
+	listener, err := listen(addr, 0)
+	if err == nil {
+		listener.Close()
+		t.Fatal("listen on a busy address succeeded")
+	}
+	if !errors.Is(err, syscall.EADDRINUSE) {
+		t.Errorf("err = %v, want EADDRINUSE", err)
+	}
+	if msg := err.Error(); !strings.Contains(msg, addr+" уже занят") || !strings.Contains(msg, "SERVER_ADDR") {
+		t.Errorf("err = %q, want a hint naming the address and SERVER_ADDR", msg)
+	}
+}
+
+func TestListenRetriesUntilAddressIsFree(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := busy.Addr().String()
+	// Адрес освобождается до первой повторной попытки
+	time.AfterFunc(listenRetryDelay/5, func() { busy.Close() })
+
+	listener, err := listen(addr, 1)
+	if err != nil {
+		t.Fatalf("listen with a retry: %v", err)
+	}
+	listener.Close()
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestFormatLogfmt(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.FixedZone("MSK", 3*3600))
+	tests := []struct {
+		entry LogEntry
+		want  string
+	}{
+		{LogEntry{levelInfo, "Запрос задачи #1", "abc123"}, `ts=2026-01-02T00:04:05Z level=info msg="Запрос задачи #1" request_id=abc123` + "\n"},
+		{LogEntry{levelInfo, "ok", ""}, "ts=2026-01-02T00:04:05Z level=info msg=ok\n"},
+		{LogEntry{levelWarn, `say "hi"`, "a b"}, `ts=2026-01-02T00:04:05Z level=warn msg="say \"hi\"" request_id="a b"` + "\n"},
+		{LogEntry{levelInfo, "a=b", ""}, `ts=2026-01-02T00:04:05Z level=info msg="a=b"` + "\n"},
+		{LogEntry{levelInfo, `C:\tmp`, ""}, `ts=2026-01-02T00:04:05Z level=info msg="C:\\tmp"` + "\n"},
+		{LogEntry{levelInfo, "line\nbreak", ""}, `ts=2026-01-02T00:04:05Z level=info msg="line\nbreak"` + "\n"},
+		{LogEntry{levelInfo, "", ""}, `ts=2026-01-02T00:04:05Z level=info msg=""` + "\n"},
+	}
+	for _, tt := range tests {
+		if got := formatLogfmt(ts, tt.entry); got != tt.want {
+			t.Errorf("formatLogfmt(%+v) =\n%q, want\n%q", tt.entry, got, tt.want)
+		}
+	}
+}
+
+func TestLogEntriesCarryRequestID(t *testing.T) {
+	config, _, err := LoadConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logChan := make(chan LogEntry, 10)
+	service := NewTaskService(NewTaskStorage(), logChan, nil, config)
+	router := NewRouter(NewTaskHandler(service), service.config)
+
+	serve(router, "GET", "/tasks", "", requestIDHeader, "req-42")
+	w := serve(router, "GET", "/tasks", "")
+	service.CloseLog()
+
+	first, second := <-logChan, <-logChan
+	if first.RequestID != "req-42" || first.Level != levelInfo {
+		t.Errorf("entry for X-Request-ID req-42 = %+v", first)
+	}
+	if generated := w.Header().Get(requestIDHeader); second.RequestID == "" || second.RequestID != generated {
+		t.Errorf("entry request_id = %q, want generated %q", second.RequestID, generated)
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("GET /tasks: status %d", w.Code)
+	}
+}
+
+// Буфер, безопасный для записи из другой горутины
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatchLogBackpressure(t *testing.T) {
+	logChan := make(chan LogEntry, 10)
+	var out syncBuffer
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		WatchLogBackpressure(logChan, 80, 5*time.Millisecond, &out, done)
+		close(stopped)
+	}()
+
+	for range 7 {
+		logChan <- LogEntry{Level: levelInfo, Message: "m"}
+	}
+	time.Sleep(30 * time.Millisecond)
+	if got := out.String(); got != "" {
+		t.Fatalf("warning below the threshold: %q", got)
+	}
+
+	logChan <- LogEntry{Level: levelInfo, Message: "m"}
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(out.String(), "WARN канал логов заполнен на 80% (8 из 10)") {
+		if time.Now().After(deadline) {
+			t.Fatalf("no backpressure warning, output %q", out.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(done)
+	<-stopped
+}
+
+// Приёмник, на котором всегда происходит ошибка записи (закрытый pipe)
+type failingWriter struct{ calls atomic.Int64 }
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.calls.Add(1)
+	return 0, errors.New("broken pipe")
+}
+
+// Подмена глобального приёмника лога на время теста
+func swapLogSink(t *testing.T, sink *LogSink) {
+	t.Helper()
+	saved := logSink
+	logSink = sink
+	t.Cleanup(func() { logSink = saved })
+}
+
+func TestLogSinkFailingWriter(t *testing.T) {
+	writer := &failingWriter{}
+	var errOut syncBuffer
+	sink := &LogSink{out: log.New(writer, "", 0), errOut: &errOut}
+	swapLogSink(t, sink)
+
+	logChan := make(chan LogEntry, 100)
+	stopped := make(chan struct{})
+	go func() {
+		Logger(logChan)
+		close(stopped)
+	}()
+	const sent = 50
+	for i := range sent {
+		logChan <- LogEntry{Level: levelInfo, Message: strconv.Itoa(i)}
+	}
+	close(logChan)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Logger did not return after the channel was closed")
+	}
+
+	if got := strings.Count(errOut.String(), "Ошибка записи лога"); got != 1 {
+		t.Errorf("error reported %d times, want once: %q", got, errOut.String())
+	}
+	// После первой ошибки записи в приёмник больше не выполняются
+	if got := writer.calls.Load(); got != 1 {
+		t.Errorf("writer called %d times, want 1", got)
+	}
+	if written, discarded := sink.written.Load(), sink.discarded.Load(); written != 0 || discarded != sent {
+		t.Errorf("written %d, discarded %d, want 0 and %d", written, discarded, sent)
+	}
+}
+
+// Приёмник лога в буфер без префикса времени
+func bufferLogSink(t *testing.T) *syncBuffer {
+	t.Helper()
+	var buf syncBuffer
+	swapLogSink(t, &LogSink{out: log.New(&buf, "", 0)})
+	return &buf
+}
+
+func TestSyncLoggingOrder(t *testing.T) {
+	buf := bufferLogSink(t)
+	config, _, err := LoadConfig([]string{"SYNC_LOGGING=true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logChan := make(chan LogEntry, 10)
+	service := NewTaskService(NewTaskStorage(), logChan, nil, config)
+	router := NewRouter(NewTaskHandler(service), service.config)
+
+	var want []string
+	for i := 1; i <= 20; i++ {
+		serve(router, "POST", "/tasks", `{"title": "t`+strconv.Itoa(i)+`"}`)
+		want = append(want, "Создана новая задача: t"+strconv.Itoa(i))
+		serve(router, "GET", "/tasks/"+strconv.Itoa(i), "")
+		want = append(want, "Запрос задачи #"+strconv.Itoa(i))
+		// Запись попадает в лог до возврата из обработчика
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != len(want) {
+			t.Fatalf("after request %d: %d log lines, want %d", i, len(lines), len(want))
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for i, line := range lines {
+		if !strings.Contains(line, "[ЛОГ] "+want[i]+" request_id=") {
+			t.Errorf("line %d = %q, want message %q", i, line, want[i])
+		}
+	}
+	if len(logChan) != 0 {
+		t.Errorf("%d entries went through the channel with SYNC_LOGGING", len(logChan))
+	}
+}
+
+func TestLogEntryWithNewlinesStaysOnOneLine(t *testing.T) {
+	for _, format := range []string{"text", "logfmt"} {
+		t.Run(format, func(t *testing.T) {
+			buf := bufferLogSink(t)
+			logSink.logfmt = format == "logfmt"
+			service := newTestService(t, "SYNC_LOGGING=true")
+			router := NewRouter(NewTaskHandler(service), service.config)
+
+			body, _ := json.Marshal(map[string]string{"title": "a\nb\r\nc\u2028d"})
+			if w := serve(router, "POST", "/tasks", string(body)); w.Code != http.StatusCreated {
+				t.Fatalf("POST /tasks: status %d", w.Code)
+			}
+			out := buf.String()
+			if strings.Count(out, "\n") != 1 || strings.ContainsAny(out, "\r\u2028") {
+				t.Fatalf("log output spans several lines: %q", out)
+			}
+			if !strings.Contains(out, `a\nb\r\nc\u2028d`) {
+				t.Errorf("log line %q lacks the escaped title", out)
+			}
+		})
+	}
+}
+
+func TestTruncateLogMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		limit   int
+		want    string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"0123456789abc", 10, "0123456789… (обрезано, всего 13 байт)"},
+		{"0123456789abc", 0, "0123456789abc"},
+		// "яя" — 4 байта: граница на середине второго символа сдвигается назад
+		{"яяя", 3, "я… (обрезано, всего 6 байт)"},
+		{"яяя", 4, "яя… (обрезано, всего 6 байт)"},
+		{"🎉x", 2, "… (обрезано, всего 5 байт)"},
+	}
+	for _, tt := range tests {
+		got := truncateLogMessage(tt.message, tt.limit)
+		if got != tt.want {
+			t.Errorf("truncateLogMessage(%q, %d) = %q, want %q", tt.message, tt.limit, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("truncateLogMessage(%q, %d) = %q is not valid UTF-8", tt.message, tt.limit, got)
+		}
+	}
+}
+
+func TestOversizedLogMessageIsTruncatedBeforeSend(t *testing.T) {
+	config, _, err := LoadConfig([]string{"MAX_LOG_MESSAGE_BYTES=64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logChan := make(chan LogEntry, 1)
+	service := NewTaskService(NewTaskStorage(), logChan, nil, config)
+	message := "Создана новая задача: " + strings.Repeat("ж", 1<<20)
+	service.Log(context.Background(), message)
+	entry := <-logChan
+	service.CloseLog()
+	note := "… (обрезано, всего " + strconv.Itoa(len(message)) + " байт)"
+	if !strings.HasSuffix(entry.Message, note) || len(entry.Message) > 64+len(note) {
+		t.Errorf("entry of %d bytes: %q", len(entry.Message), entry.Message)
+	}
+}
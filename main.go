@@ -3,34 +3,107 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
 	"log"
+	"mime"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 // Структура задачи
 type Task struct {
-	ID        int       `json:"id"`         // Идентификатор
-	Title     string    `json:"title"`      // Название задачи
-	Completed bool      `json:"completed"`  // Статус выполнения
-	CreatedAt time.Time `json:"created_at"` // Время создания
+	ID        int        `json:"id"`                 // Идентификатор
+	Title     string     `json:"title"`              // Название задачи
+	Completed bool       `json:"completed"`          // Статус выполнения
+	CreatedAt time.Time  `json:"created_at"`         // Время создания
+	DueDate   *time.Time `json:"due_date,omitempty"` // Срок выполнения
+	Overdue   bool       `json:"overdue"`            // Просрочена (вычисляется при выдаче)
+	Version   int        `json:"version"`            // Версия, растёт при каждом изменении
+	Progress  int        `json:"progress"`           // Прогресс выполнения, 0–100
+	Order     float64    `json:"order"`              // Позиция в ручном порядке (sort=order)
+	// Время последнего перехода в выполненное состояние
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// Номер изменения хранилища, на котором задача изменилась последней
+	ChangeSeq uint64 `json:"-"`
+}
+
+// Допустимые границы прогресса
+const (
+	minProgress = 0
+	maxProgress = 100
+)
+
+// Краткий статус задачи
+type TaskStatus struct {
+	Completed bool `json:"completed"`
+	Version   int  `json:"version"`
+}
+
+// Задача с указанным ID отсутствует в хранилище
+var ErrTaskNotFound = errors.New("задача не найдена")
+
+// Интерфейс хранилища задач
+type Storage interface {
+	Create(task Task) Task
+	GetByID(id int) (Task, bool)
+	GetAll(filter TaskFilter) []Task
+	Count(completed *bool) int
+	LastModified() time.Time
+	// Счётчик изменений: растёт при каждом изменении любой задачи
+	Sequence() uint64
+	// ID, который получит следующая созданная задача
+	NextID() int
+	// Группировка задач по ключу за один проход
+	GroupBy(filter TaskFilter, key func(Task) string) map[string][]Task
+	Update(id int, fn func(task *Task) error) (Task, error)
+	// Перемещение задачи перед или после другой задачи
+	Reorder(id, anchor int, before bool) (Task, error)
+	// Сохранение пачки импортируемых задач с учётом политики конфликтов ID
+	Import(tasks []Task, policy ConflictPolicy) ImportCounts
+	// Пакетное завершение задач с проверкой версий под одной блокировкой
+	CompleteBatch(items []VersionedID) []BulkCompleteResult
+	// Статусы найденных задач из списка ID
+	Statuses(ids []int) map[int]TaskStatus
+	// Проверка доступности хранилища
+	Ping(ctx context.Context) error
 }
 
 type TaskStorage struct {
-	mu     sync.RWMutex
-	tasks  map[int]Task
-	nextID int
+	mu        sync.RWMutex
+	tasks     map[int]Task
+	nextID    int
+	now       func() time.Time // Источник текущего времени
+	completed int              // Количество выполненных задач
+	modified  time.Time        // Время последнего изменения хранилища
+	sequence  uint64           // Счётчик изменений хранилища
+	maxOrder  float64          // Верхняя граница поля order среди задач
+	// ID задач, изменённых с последней публикации снимка
+	// (только для писателя ActorStorage, иначе nil)
+	changed map[int]struct{}
 }
 
 func NewTaskStorage() *TaskStorage {
 	return &TaskStorage{
-		tasks:  make(map[int]Task),
-		nextID: 1,
+		tasks:    make(map[int]Task),
+		nextID:   1,
+		now:      time.Now,
+		modified: time.Now().UTC(),
 	}
 }
 
@@ -39,10 +112,55 @@ func (s *TaskStorage) Create(task Task) Task {
 	defer s.mu.Unlock()
 
 	task.ID = s.nextID
-	task.CreatedAt = time.Now()
-	s.tasks[task.ID] = task
+	task.CreatedAt = s.now()
+	task.Order = s.maxOrder + orderGap
+	task.Version = 1
+	task.CompletedAt = nil
+	s.trackCompletion(false, &task)
+	s.put(task)
 	s.nextID++
-	return task
+	return s.tasks[task.ID]
+}
+
+// Обновление CompletedAt по переходу статуса выполнения:
+// false→true фиксирует текущее время, true→false очищает,
+// без смены статуса значение не меняется
+func (s *TaskStorage) trackCompletion(wasCompleted bool, task *Task) {
+	switch {
+	case task.Completed && !wasCompleted:
+		now := s.now()
+		task.CompletedAt = &now
+	case !task.Completed:
+		task.CompletedAt = nil
+	}
+}
+
+// Запись задачи с пересчётом счётчиков (вызывается под блокировкой записи).
+// Все моменты времени хранятся в UTC независимо от зоны, в которой их прислал клиент.
+func (s *TaskStorage) put(task Task) {
+	task.CreatedAt = task.CreatedAt.UTC()
+	if task.DueDate != nil {
+		dueDate := task.DueDate.UTC()
+		task.DueDate = &dueDate
+	}
+	if task.CompletedAt != nil {
+		completedAt := task.CompletedAt.UTC()
+		task.CompletedAt = &completedAt
+	}
+	if old, exists := s.tasks[task.ID]; exists && old.Completed {
+		s.completed--
+	}
+	if task.Completed {
+		s.completed++
+	}
+	s.maxOrder = max(s.maxOrder, task.Order)
+	s.sequence++
+	task.ChangeSeq = s.sequence
+	s.tasks[task.ID] = task
+	if s.changed != nil {
+		s.changed[task.ID] = struct{}{}
+	}
+	s.modified = s.now().UTC()
 }
 
 func (s *TaskStorage) GetByID(id int) (Task, bool) {
@@ -52,27 +170,394 @@ func (s *TaskStorage) GetByID(id int) (Task, bool) {
 	return task, exists
 }
 
-func (s *TaskStorage) GetAll(completed *bool) []Task {
+// Атомарное изменение задачи: fn получает копию и может её изменить.
+// Если fn возвращает ошибку, хранилище остаётся без изменений.
+func (s *TaskStorage) Update(id int, fn func(task *Task) error) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, exists := s.tasks[id]
+	if !exists {
+		return Task{}, ErrTaskNotFound
+	}
+	previous := task
+	if err := fn(&task); err != nil {
+		return Task{}, err
+	}
+	task.CompletedAt = previous.CompletedAt
+	s.trackCompletion(previous.Completed, &task)
+	task.Version++
+	s.put(task)
+	return s.tasks[id], nil
+}
+
+func (s *TaskStorage) GetAll(filter TaskFilter) []Task {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	result := []Task{}
 	for _, task := range s.tasks {
-		if completed == nil || task.Completed == *completed {
+		if filter.Matches(task) {
 			result = append(result, task)
 		}
 	}
 	return result
 }
 
+// Импорт сохраняет ID задач (новый ID выдаётся при ID <= 0),
+// совпадения с существующими ID разрешаются согласно policy
+func (s *TaskStorage) Import(tasks []Task, policy ConflictPolicy) ImportCounts {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var counts ImportCounts
+	for _, task := range tasks {
+		version := 1
+		if old, exists := s.tasks[task.ID]; exists {
+			switch policy {
+			case ConflictSkip:
+				counts.Skipped++
+				continue
+			case ConflictOverwrite:
+				// Версия продолжает расти, чтобы проверки версий
+				// не приняли устаревшие запросы после перезаписи
+				version = old.Version + 1
+				counts.Overwritten++
+			case ConflictReassign:
+				task.ID = 0
+				counts.Reassigned++
+			}
+		}
+		if task.ID <= 0 {
+			task.ID = s.nextID
+		}
+		if task.ID >= s.nextID {
+			s.nextID = task.ID + 1
+		}
+		// Время создания из импорта сохраняется, чтобы восстановление
+		// из выгрузки не сдвигало историю задач
+		if task.CreatedAt.IsZero() {
+			task.CreatedAt = s.now()
+		}
+		if task.Order <= 0 {
+			task.Order = s.maxOrder + orderGap
+		}
+		task.Version = version
+		// Время выполнения из импорта сохраняется, если задача выполнена
+		if task.CompletedAt == nil {
+			s.trackCompletion(false, &task)
+		} else if !task.Completed {
+			task.CompletedAt = nil
+		}
+		s.put(task)
+		counts.Imported++
+	}
+	return counts
+}
+
+func (s *TaskStorage) GroupBy(filter TaskFilter, key func(Task) string) map[string][]Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := map[string][]Task{}
+	for _, task := range s.tasks {
+		if filter.Matches(task) {
+			k := key(task)
+			result[k] = append(result[k], task)
+		}
+	}
+	return result
+}
+
+func (s *TaskStorage) NextID() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.nextID
+}
+
+// Время последнего изменения любой задачи
+func (s *TaskStorage) LastModified() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.modified
+}
+
+func (s *TaskStorage) Sequence() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sequence
+}
+
+// Количество задач за O(1) по счётчикам
+func (s *TaskStorage) Count(completed *bool) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	switch {
+	case completed == nil:
+		return len(s.tasks)
+	case *completed:
+		return s.completed
+	default:
+		return len(s.tasks) - s.completed
+	}
+}
+
+// Ссылка на задачу в ожидаемой версии
+type VersionedID struct {
+	ID      int `json:"id"`
+	Version int `json:"version"`
+}
+
+// Итоги пакетного завершения
+const (
+	BulkUpdated         = "updated"
+	BulkVersionMismatch = "version_mismatch"
+	BulkNotFound        = "not_found"
+)
+
+// Результат пакетного завершения для одной задачи
+type BulkCompleteResult struct {
+	ID      int    `json:"id"`
+	Status  string `json:"status"`            // updated, version_mismatch или not_found
+	Version int    `json:"version,omitempty"` // Текущая версия найденной задачи
+}
+
+// Весь пакет обрабатывается под одной блокировкой записи, поэтому
+// между проверкой версий и изменением задачи не могут вклиниться другие запросы
+func (s *TaskStorage) CompleteBatch(items []VersionedID) []BulkCompleteResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]BulkCompleteResult, len(items))
+	for i, item := range items {
+		task, exists := s.tasks[item.ID]
+		switch {
+		case !exists:
+			results[i] = BulkCompleteResult{ID: item.ID, Status: BulkNotFound}
+		case task.Version != item.Version:
+			results[i] = BulkCompleteResult{ID: item.ID, Status: BulkVersionMismatch, Version: task.Version}
+		default:
+			wasCompleted := task.Completed
+			task.Completed = true
+			s.trackCompletion(wasCompleted, &task)
+			task.Version++
+			s.put(task)
+			results[i] = BulkCompleteResult{ID: item.ID, Status: BulkUpdated, Version: task.Version}
+		}
+	}
+	return results
+}
+
+func (s *TaskStorage) Statuses(ids []int) map[int]TaskStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[int]TaskStatus, len(ids))
+	for _, id := range ids {
+		if task, exists := s.tasks[id]; exists {
+			result[id] = TaskStatus{task.Completed, task.Version}
+		}
+	}
+	return result
+}
+
+// Хранилище в памяти всегда доступно
+func (s *TaskStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
 type TaskService struct {
-	store   *TaskStorage  // Ссылка на хранилище
-	logChan chan<- string // Канал для логов
+	store       Storage           // Ссылка на хранилище
+	logChan     chan<- LogEntry   // Канал для логов
+	idempotency *IdempotencyStore // Ключи идемпотентности создания
+	config      Config            // Настройки
+	now         func() time.Time  // Источник текущего времени
+	throttle    *LogThrottle      // Ограничение повторяющихся сообщений
+	validator   *TaskValidator    // Правила проверки задач
+	snapshots   *SnapshotStore    // Снимки для согласованного чтения
+	importMu    sync.Mutex        // Одновременно выполняется только один импорт
+
+	// Остановка логирования: после closing новые сообщения отбрасываются,
+	// а sendMu не даёт закрыть канал, пока идут начатые отправки
+	closing atomic.Bool
+	sendMu  sync.RWMutex
+	dropped atomic.Int64 // Сообщения, отброшенные после начала остановки
+
+	// Выборка сообщений при LOG_SAMPLE_RATE
+	sampleCount atomic.Uint64 // Сообщения, прошедшие через выборку
+	sampleNoted atomic.Int64  // Время последней пометки о выборке (UnixNano)
 }
 
 // Конструктор сервиса
-func NewTaskService(store *TaskStorage, logChan chan<- string) *TaskService {
-	return &TaskService{store, logChan}
+func NewTaskService(store Storage, logChan chan<- LogEntry, idempotency *IdempotencyStore, config Config) *TaskService {
+	s := &TaskService{
+		store:       store,
+		logChan:     logChan,
+		idempotency: idempotency,
+		config:      config,
+		now:         time.Now,
+		snapshots:   NewSnapshotStore(config.SnapshotTTL),
+	}
+	s.validator = NewTaskValidator(config, func() time.Time { return s.now() })
+	s.throttle = NewLogThrottle(config.LogThrottleInterval, func(entry LogEntry, suppressed int) {
+		entry.Message += suppressedNote(suppressed)
+		s.emit(entry)
+	})
+	return s
+}
+
+// Уровни записей лога
+const (
+	levelInfo = "info"
+	levelWarn = "warn"
+)
+
+// Запись асинхронного лога
+type LogEntry struct {
+	Level     string // levelInfo или levelWarn
+	Message   string
+	RequestID string // X-Request-ID запроса, при обработке которого сделана запись
+}
+
+// Отправка сообщения в лог: асинхронно через канал
+// или сразу в вызывающей горутине при SYNC_LOGGING.
+// Запись получает идентификатор запроса из ctx.
+// Одинаковые сообщения пишутся не чаще LOG_THROTTLE_INTERVAL,
+// слишком длинные обрезаются до MAX_LOG_MESSAGE_BYTES.
+func (s *TaskService) Log(ctx context.Context, message string) {
+	s.log(LogEntry{levelInfo, message, requestIDFrom(ctx)})
+}
+
+// Отправка предупреждения: то же, что Log, но с уровнем warn
+// и без выборки LOG_SAMPLE_RATE, чтобы отказы и ошибки не терялись
+func (s *TaskService) LogWarn(ctx context.Context, message string) {
+	s.log(LogEntry{levelWarn, message, requestIDFrom(ctx)})
+}
+
+func (s *TaskService) log(entry LogEntry) {
+	entry.Message = truncateLogMessage(entry.Message, s.config.MaxLogMessageBytes)
+	allow, suppressed := s.throttle.Allow(entry)
+	if !allow {
+		return
+	}
+	if suppressed > 0 {
+		entry.Message += suppressedNote(suppressed)
+	}
+	s.emit(entry)
+}
+
+// Пометка о числе подавленных повторов сообщения
+func suppressedNote(suppressed int) string {
+	return " (подавлено повторов: " + strconv.Itoa(suppressed) + ")"
+}
+
+// Запись сообщения, прошедшего ограничение частоты: выборка
+// (кроме предупреждений), затем синхронная запись или отправка в канал
+func (s *TaskService) emit(entry LogEntry) {
+	if entry.Level != levelWarn {
+		var allow bool
+		entry.Message, allow = s.sample(entry.Message)
+		if !allow {
+			return
+		}
+	}
+	if s.config.SyncLogging {
+		writeLogEntry(entry)
+		return
+	}
+	if s.closing.Load() {
+		s.dropped.Add(1)
+		return
+	}
+	s.sendMu.RLock()
+	defer s.sendMu.RUnlock()
+	// Повторная проверка: остановка могла начаться до захвата блокировки
+	if s.closing.Load() {
+		s.dropped.Add(1)
+		return
+	}
+	s.logChan <- entry
+}
+
+// Остановка асинхронного логирования: новые сообщения отбрасываются,
+// начатые отправки завершаются, после чего канал закрывается.
+// Возвращает число сообщений, отброшенных с начала остановки.
+func (s *TaskService) CloseLog() int64 {
+	s.closing.Store(true)
+	s.sendMu.Lock()
+	close(s.logChan)
+	s.sendMu.Unlock()
+	return s.dropped.Load()
+}
+
+// Обрезка сообщения до limit байт по границе символа с пометкой
+// об исходной длине; limit <= 0 отключает ограничение
+func truncateLogMessage(message string, limit int) string {
+	if limit <= 0 || len(message) <= limit {
+		return message
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(message[cut]) {
+		cut--
+	}
+	return message[:cut] + "… (обрезано, всего " + strconv.Itoa(len(message)) + " байт)"
+}
+
+// Нормализация названия при NORMALIZE_TITLES: Unicode-форма NFC
+// (e + комбинируемый акцент становится é), затем последовательности
+// пробельных символов заменяются одним пробелом, края обрезаются.
+func (s *TaskService) normalizeTitle(task *Task) {
+	if s.config.NormalizeTitles {
+		task.Title = strings.Join(strings.Fields(norm.NFC.String(task.Title)), " ")
+	}
+}
+
+// Как часто к записи добавляется пометка о выборке
+const logSampleNoteInterval = time.Minute
+
+// Выборка сообщений лога при LOG_SAMPLE_RATE=N: пишется каждое N-е
+// сообщение, а не чаще раза в минуту к записи добавляется пометка,
+// чтобы читающий лог знал, что видит только часть событий.
+// Предупреждения (LogWarn и WARN в stderr) выборке не подлежат.
+func (s *TaskService) sample(message string) (string, bool) {
+	rate := uint64(s.config.LogSampleRate)
+	if rate <= 1 {
+		return message, true
+	}
+	if (s.sampleCount.Add(1)-1)%rate != 0 {
+		return message, false
+	}
+	now := s.now().UnixNano()
+	if last := s.sampleNoted.Load(); (last == 0 || now-last >= int64(logSampleNoteInterval)) && s.sampleNoted.CompareAndSwap(last, now) {
+		message += " (в лог пишется 1 из " + strconv.FormatUint(rate, 10) + " сообщений)"
+	}
+	return message, true
+}
+
+// Отметка выполнения по префиксу названия при DONE_TITLE_PREFIX:
+// задача "[x] Купить молоко" создаётся выполненной с названием "Купить молоко"
+func (s *TaskService) applyDoneMarker(task *Task) {
+	if marker := s.config.DoneTitlePrefix; marker != "" {
+		if title, ok := strings.CutPrefix(task.Title, marker); ok {
+			task.Title = title
+			task.Completed = true
+		}
+	}
+}
+
+// Заполнение вычисляемых полей перед выдачей клиенту
+func (s *TaskService) present(task Task) Task {
+	task.Overdue = task.DueDate != nil && !task.Completed && task.DueDate.Before(s.now())
+	return task
+}
+
+// Заполнение вычисляемых полей для списка задач
+func (s *TaskService) presentAll(tasks []Task) []Task {
+	for i := range tasks {
+		tasks[i] = s.present(tasks[i])
+	}
+	return tasks
 }
 
 type TaskHandler struct {
@@ -84,102 +569,792 @@ func NewTaskHandler(service *TaskService) *TaskHandler {
 	return &TaskHandler{service}
 }
 
+// Ошибка разбора ID задачи из URL
+var ErrInvalidID = errors.New("некорректный ID")
+
+// ID задачи из URL в каноническом виде: окружающие пробелы отбрасываются,
+// допускаются только десятичные цифры без знака и ведущих нулей,
+// поэтому /tasks/7 и /tasks/%207 совпадают, а /tasks/007 и /tasks/+7 отклоняются
+func parseTaskID(r *http.Request) (int, error) {
+	value := strings.TrimSpace(r.PathValue("id"))
+	if value == "" || value[0] < '1' || value[0] > '9' {
+		return 0, ErrInvalidID
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, ErrInvalidID
+	}
+	return id, nil
+}
+
+// Ошибка строгого разбора тела: после JSON-значения есть лишние данные
+var ErrTrailingData = errors.New("лишние данные после JSON")
+
+// Декодирование JSON-значения из тела запроса. В строгом режиме
+// (STRICT_JSON) тело вида {...}{...} или {...}мусор отклоняется.
+func decodeJSON(r io.Reader, v any, strict bool) error {
+	dec := json.NewDecoder(r)
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if strict {
+		if _, err := dec.Token(); err != io.EOF {
+			return ErrTrailingData
+		}
+	}
+	return nil
+}
+
+// Ответ на ошибку разбора тела: превышение лимита тела (в том числе
+// распакованного gzip, MAX_DECODED_BODY_BYTES) — 413, иначе 400 с message
+func writeDecodeError(w http.ResponseWriter, err error, message string) {
+	var bodyTooLarge *http.MaxBytesError
+	if errors.As(err, &bodyTooLarge) {
+		http.Error(w, "Тело запроса превышает "+strconv.FormatInt(bodyTooLarge.Limit, 10)+" байт", http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, message, http.StatusBadRequest)
+}
+
+// ETag списка по времени изменения хранилища и параметрам запроса
+// (Encode упорядочивает параметры, поэтому их порядок в URL не важен).
+// Вычисляется без обхода задач.
+func listETag(modified time.Time, query url.Values) string {
+	hash := fnv.New64a()
+	hash.Write([]byte(strconv.FormatInt(modified.UnixNano(), 10) + "?" + query.Encode()))
+	return `"` + strconv.FormatUint(hash.Sum64(), 16) + `"`
+}
+
+// Совпадение ETag с одним из значений If-None-Match
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Заголовки Last-Modified и ETag списка и проверка условного запроса;
+// true означает, что ответ 304 уже отправлен
+func (h *TaskHandler) notModified(w http.ResponseWriter, r *http.Request) bool {
+	// Время изменения читается до списка: если между ними произойдёт
+	// изменение, клиент получит более старую метку и просто запросит список снова
+	modified := h.service.store.LastModified()
+	etag := listETag(modified, r.URL.Query())
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+	// If-None-Match имеет приоритет над If-Modified-Since
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		if !etagMatches(match, etag) {
+			return false
+		}
+	} else if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err != nil || modified.Truncate(time.Second).After(since) {
+		return false
+	} else if h.service.now().Sub(modified) < time.Second {
+		// Точность If-Modified-Since — секунда: пока не прошла секунда
+		// с последнего изменения, клиент мог не увидеть изменения
+		// той же секунды, поэтому 304 не отдаётся
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// JSON-ответ для наборов задач. Если тело больше MAX_RESPONSE_BYTES,
+// вместо него возвращается 500, чтобы не отправлять клиенту
+// неожиданно огромный ответ.
+func (h *TaskHandler) writeJSON(w http.ResponseWriter, r *http.Request, code int, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "Ошибка формирования ответа", http.StatusInternalServerError)
+		return
+	}
+	if limit := h.service.config.MaxResponseBytes; limit > 0 && len(data) > limit {
+		h.service.LogWarn(r.Context(), "Ответ отклонён: "+strconv.Itoa(len(data))+" байт превышает MAX_RESPONSE_BYTES")
+		http.Error(w, "Ответ превышает допустимый размер, уточните запрос", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(append(data, '\n'))
+}
+
+// Заголовок с текущим значением счётчика изменений хранилища
+const changeSequenceHeader = "X-Change-Sequence"
+
 // Обработчик GET /tasks
 func (h *TaskHandler) GetTasks(w http.ResponseWriter, r *http.Request) {
-	// Парсинг параметра фильтра
-	var filter *bool
-	if param := r.URL.Query().Get("completed"); param != "" {
-		val, err := strconv.ParseBool(param)
-		if err == nil {
-			filter = &val
+	// Парсинг параметров фильтра, сортировки и проекции
+	filter := parseTaskFilter(r)
+	sortField, sortOrder, err := parseSort(r.URL.Query(), h.service.config.SortDefaults)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fields, err := parseFields(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// ids_only выдаёт только ID задач после фильтрации и сортировки,
+	// поэтому выбор полей вместе с ним не имеет смысла
+	idsOnly := parseBoolParam(r, "ids_only")
+	if idsOnly != nil && *idsOnly && fields != nil {
+		http.Error(w, "Параметры ids_only и fields несовместимы", http.StatusBadRequest)
+		return
+	}
+	if h.service.config.FiltersHeader {
+		w.Header().Set("X-Applied-Filters", filter.String()+"; sort="+sortField+"; order="+sortOrder)
+	}
+	// Курсор для since читается до выборки: изменение между ними
+	// попадёт и в этот ответ, и в следующий, но не потеряется
+	sequence := strconv.FormatUint(h.service.store.Sequence(), 10)
+	var tasks []Task
+	if token := r.URL.Query().Get("snapshot"); token != "" {
+		// Чтение из ранее созданного снимка
+		snapshot, ok := h.service.snapshots.Get(token)
+		if !ok {
+			http.Error(w, "Снимок не найден или истёк", http.StatusGone)
+			return
+		}
+		tasks = filterTasks(snapshot.Tasks, filter)
+	} else {
+		w.Header().Set(changeSequenceHeader, sequence)
+		if h.notModified(w, r) {
+			return
+		}
+		tasks = h.service.store.GetAll(filter)
+	}
+	tasks = h.service.presentAll(tasks)
+	sortTasks(tasks, sortField, sortOrder)
+	// Асинхронное логирование
+	h.service.Log(r.Context(), "Запрос всех задач: найдено "+strconv.Itoa(len(tasks)))
+	// Формирование ответа
+	if idsOnly != nil && *idsOnly {
+		ids := make([]int, len(tasks))
+		for i, task := range tasks {
+			ids[i] = task.ID
 		}
+		h.writeJSON(w, r, http.StatusOK, ids)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, projectTasks(tasks, fields))
+}
+
+// Поля, по которым доступна группировка
+var groupKeys = map[string]func(Task) string{
+	"completed": func(task Task) string { return strconv.FormatBool(task.Completed) },
+}
+
+// Обработчик GET /tasks/grouped
+func (h *TaskHandler) GetGroupedTasks(w http.ResponseWriter, r *http.Request) {
+	key, ok := groupKeys[r.URL.Query().Get("by")]
+	if !ok {
+		http.Error(w, "Неподдерживаемое поле группировки", http.StatusBadRequest)
+		return
+	}
+	// Группировка с учётом фильтра
+	groups := h.service.store.GroupBy(parseTaskFilter(r), key)
+	for name, tasks := range groups {
+		groups[name] = h.service.presentAll(tasks)
+	}
+	// Асинхронное логирование
+	h.service.Log(r.Context(), "Запрос сгруппированных задач: групп "+strconv.Itoa(len(groups)))
+	// Формирование ответа
+	h.writeJSON(w, r, http.StatusOK, groups)
+}
+
+// Обработчик GET /tasks/count
+func (h *TaskHandler) CountTasks(w http.ResponseWriter, r *http.Request) {
+	// Парсинг фильтра: только по статусу считается по счётчикам за O(1),
+	// остальные фильтры списка требуют выборки; fields и sort не влияют
+	filter := parseTaskFilter(r)
+	var count int
+	if (filter == TaskFilter{Completed: filter.Completed}) {
+		count = h.service.store.Count(filter.Completed)
+	} else {
+		count = len(h.service.store.GetAll(filter))
 	}
-	// Получение задач из хранилища
-	tasks := h.service.store.GetAll(filter)
 	// Асинхронное логирование
-	h.service.logChan <- "Запрос всех задач: найдено " + strconv.Itoa(len(tasks))
+	h.service.Log(r.Context(), "Запрос количества задач: "+strconv.Itoa(count))
 	// Формирование ответа
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(tasks)
+	json.NewEncoder(w).Encode(map[string]int{"count": count})
 }
 
 // Обработчик GET /tasks/{id}
 func (h *TaskHandler) GetTaskByID(w http.ResponseWriter, r *http.Request) {
 	// Парсинг ID из URL
-	id, err := strconv.Atoi(r.PathValue("id"))
+	id, err := parseTaskID(r)
 	if err != nil {
 		http.Error(w, "Некорректный ID", http.StatusBadRequest)
 		return
 	}
+	// Парсинг списка полей
+	fields, err := parseFields(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	// Поиск задачи
+	w.Header().Set(changeSequenceHeader, strconv.FormatUint(h.service.store.Sequence(), 10))
 	task, exists := h.service.store.GetByID(id)
 	if !exists {
 		http.Error(w, "Задача не найдена", http.StatusNotFound)
 		return
 	}
 	// Асинхронное логирование
-	h.service.logChan <- "Запрос задачи #" + strconv.Itoa(id)
+	h.service.Log(r.Context(), "Запрос задачи #"+strconv.Itoa(id))
 	// Формирование ответа
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(task)
+	json.NewEncoder(w).Encode(projectTask(h.service.present(task), fields))
 }
 
 // Обработчик POST /tasks
 func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 	// Декодирование тела запроса
 	var newTask Task
-	if err := json.NewDecoder(r.Body).Decode(&newTask); err != nil {
-		http.Error(w, "Неверный формат данных", http.StatusBadRequest)
+	if err := decodeJSON(r.Body, &newTask, h.service.config.StrictJSON); err != nil {
+		writeDecodeError(w, err, "Неверный формат данных")
 		return
 	}
-	// Валидация
-	if newTask.Title == "" {
-		http.Error(w, "Название задачи обязательно", http.StatusBadRequest)
+	// Нормализация и валидация
+	h.service.normalizeTitle(&newTask)
+	h.service.applyDoneMarker(&newTask)
+	if errs := h.service.validator.ValidateCreate(newTask); len(errs) > 0 {
+		h.writeValidationError(w, errs)
 		return
 	}
-	// Создание задачи
-	createdTask := h.service.store.Create(newTask)
+	// Создание задачи (однократно для ключа идемпотентности)
+	var createdTask Task
+	replayed := false
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		var err error
+		createdTask, replayed, err = h.service.idempotency.Do(r.Context(), key, requestFingerprint(newTask), func() Task {
+			return h.service.store.Create(newTask)
+		})
+		if errors.Is(err, ErrIdempotencyInProgress) {
+			http.Error(w, "Запрос с таким Idempotency-Key уже выполняется", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, ErrIdempotencyMismatch) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if err != nil {
+			// Клиент отключился, не дождавшись результата
+			return
+		}
+	} else {
+		createdTask = h.service.store.Create(newTask)
+	}
 	// Асинхронное логирование
-	h.service.logChan <- "Создана новая задача: " + createdTask.Title
+	if replayed {
+		h.service.Log(r.Context(), "Повторный запрос создания задачи #"+strconv.Itoa(createdTask.ID))
+		w.Header().Set("Idempotent-Replayed", "true")
+	} else {
+		h.service.Log(r.Context(), "Создана новая задача: "+createdTask.Title)
+	}
 	// Формирование ответа
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(createdTask)
+	json.NewEncoder(w).Encode(taskWithWarnings{h.service.present(createdTask), h.service.validator.warn(createdTask)})
 }
 
-// Асинхронный логгер
-func Logger(logChan <-chan string) {
-	for entry := range logChan {
-		log.Printf(" [ЛОГ] %s", entry)
+// Обработчик PATCH /tasks/{id} (JSON Patch, RFC 6902)
+func (h *TaskHandler) PatchTask(w http.ResponseWriter, r *http.Request) {
+	// Парсинг ID из URL
+	id, err := parseTaskID(r)
+	if err != nil {
+		http.Error(w, "Некорректный ID", http.StatusBadRequest)
+		return
+	}
+	// Проверка типа содержимого
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json-patch+json" {
+		http.Error(w, "Ожидается Content-Type: application/json-patch+json", http.StatusUnsupportedMediaType)
+		return
+	}
+	// Разбор операций
+	ops, err := ParsePatch(r.Body, h.service.config.StrictJSON)
+	if err != nil {
+		writeDecodeError(w, err, err.Error())
+		return
+	}
+	// Применение патча целиком под блокировкой хранилища
+	updatedTask, err := h.service.store.Update(id, func(task *Task) error {
+		old := *task
+		if err := ApplyPatch(task, ops); err != nil {
+			return err
+		}
+		// Нормализуется только изменяемое патчем название, чтобы
+		// правка других полей не переписывала сохранённое ранее
+		if patchesTitle(ops) {
+			h.service.normalizeTitle(task)
+		}
+		// Валидация результата
+		if errs := h.service.validator.ValidateUpdate(old, *task); len(errs) > 0 {
+			return errs
+		}
+		return nil
+	})
+	var validationErrs ValidationErrors
+	switch {
+	case errors.As(err, &validationErrs):
+		h.writeValidationError(w, validationErrs)
+		return
+	case errors.Is(err, ErrTaskNotFound):
+		http.Error(w, "Задача не найдена", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrPatchTestFailed):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Асинхронное логирование
+	h.service.Log(r.Context(), "Обновлена задача #"+strconv.Itoa(id))
+	// Формирование ответа
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(taskWithWarnings{h.service.present(updatedTask), h.service.validator.warn(updatedTask)})
+}
+
+// Шаблон, по которому отклоняются неподдерживаемые методы
+const taskFallbackPattern = "/tasks/{id}"
+
+// Методы, проверяемые при формировании заголовка Allow
+var routedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// Обработчик неподдерживаемых методов для /tasks/{id}: явный отказ
+// с подсказкой вместо стандартного ответа маршрутизатора. Allow
+// собирается по маршрутам mux, поэтому для /tasks/1 это GET, HEAD, PATCH,
+// а для соседних путей вроде /tasks/status — их собственные методы.
+func RejectUnsupportedMethod(mux *http.ServeMux) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var allow []string
+		for _, method := range routedMethods {
+			probe := r.Clone(r.Context())
+			probe.Method = method
+			if _, pattern := mux.Handler(probe); pattern != "" && pattern != taskFallbackPattern {
+				allow = append(allow, method)
+			}
+		}
+		message := "Метод " + r.Method + " не поддерживается для " + r.URL.Path
+		if _, err := parseTaskID(r); err == nil {
+			message = "Метод " + r.Method + " не поддерживается для /tasks/{id}: задачи создаются через POST /tasks, а изменяются через PATCH /tasks/{id}"
+		}
+		w.Header().Set("Allow", strings.Join(allow, ", "))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
+	}
+}
+
+// Обработчик POST /tasks/{id}/progress
+// Изменение прогресса на delta выполняется под блокировкой хранилища,
+// поэтому параллельные изменения не теряются; результат ограничен 0–100
+func (h *TaskHandler) AddProgress(w http.ResponseWriter, r *http.Request) {
+	// Парсинг ID из URL
+	id, err := parseTaskID(r)
+	if err != nil {
+		http.Error(w, "Некорректный ID", http.StatusBadRequest)
+		return
+	}
+	// Декодирование тела запроса
+	var request struct {
+		Delta *int `json:"delta"`
+	}
+	if err := decodeJSON(r.Body, &request, h.service.config.StrictJSON); err != nil || request.Delta == nil {
+		writeDecodeError(w, err, "Неверный формат данных")
+		return
+	}
+	// Больший по модулю delta всё равно упирается в границу;
+	// ограничение заранее исключает переполнение при сложении
+	const progressSpan = maxProgress - minProgress
+	delta := min(max(*request.Delta, -progressSpan), progressSpan)
+	// Атомарное изменение прогресса
+	updatedTask, err := h.service.store.Update(id, func(task *Task) error {
+		task.Progress = min(max(task.Progress+delta, minProgress), maxProgress)
+		return nil
+	})
+	if err != nil {
+		http.Error(w, "Задача не найдена", http.StatusNotFound)
+		return
+	}
+	// Асинхронное логирование
+	h.service.Log(r.Context(), "Прогресс задачи #"+strconv.Itoa(id)+": "+strconv.Itoa(updatedTask.Progress))
+	// Формирование ответа
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"progress": updatedTask.Progress})
+}
+
+// Обработчик POST /tasks/bulk-complete
+func (h *TaskHandler) BulkComplete(w http.ResponseWriter, r *http.Request) {
+	// Декодирование списка задач с ожидаемыми версиями
+	var request struct {
+		Items []VersionedID `json:"items"`
+	}
+	if err := decodeJSON(r.Body, &request, h.service.config.StrictJSON); err != nil || request.Items == nil {
+		writeDecodeError(w, err, "Неверный формат данных")
+		return
+	}
+	results := h.service.store.CompleteBatch(request.Items)
+	// Асинхронное логирование
+	updated := 0
+	for _, result := range results {
+		if result.Status == BulkUpdated {
+			updated++
+		}
+	}
+	h.service.Log(r.Context(), "Пакетное завершение задач: обновлено "+strconv.Itoa(updated)+" из "+strconv.Itoa(len(results)))
+	// Формирование ответа
+	h.writeJSON(w, r, http.StatusOK, map[string][]BulkCompleteResult{"results": results})
+}
+
+// Обработчик POST /tasks/status
+func (h *TaskHandler) GetStatuses(w http.ResponseWriter, r *http.Request) {
+	// Декодирование списка ID
+	var request struct {
+		IDs []int `json:"ids"`
+	}
+	if err := decodeJSON(r.Body, &request, h.service.config.StrictJSON); err != nil || request.IDs == nil {
+		writeDecodeError(w, err, "Неверный формат данных")
+		return
+	}
+	// Получение статусов одним проходом по хранилищу
+	statuses := h.service.store.Statuses(request.IDs)
+	// Асинхронное логирование
+	h.service.Log(r.Context(), "Запрос статусов задач: найдено "+strconv.Itoa(len(statuses))+" из "+strconv.Itoa(len(request.IDs)))
+	// Формирование ответа
+	h.writeJSON(w, r, http.StatusOK, statuses)
+}
+
+// Обработчик GET /tasks/next-id (включается ENABLE_ADMIN_ENDPOINTS)
+// ID не резервируется: параллельное создание может его занять
+func (h *TaskHandler) GetNextID(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"next_id": h.service.store.NextID()})
+}
+
+// Состояние подсистемы логирования
+type LoggerStats struct {
+	Logged   int64 `json:"logged"`   // Записано сообщений
+	Dropped  int64 `json:"dropped"`  // Отброшено при остановке или после ошибки записи
+	Length   int   `json:"length"`   // Сообщений в канале
+	Capacity int   `json:"capacity"` // Ёмкость канала
+	Alive    bool  `json:"alive"`    // Горутина логгера работает
+	Restarts int64 `json:"restarts"` // Перезапуски логгера после паники при записи
+}
+
+// Обработчик GET /debug/logger (включается ENABLE_ADMIN_ENDPOINTS)
+func (h *TaskHandler) GetLoggerStats(w http.ResponseWriter, r *http.Request) {
+	stats := LoggerStats{
+		Logged:   logSink.written.Load(),
+		Dropped:  h.service.dropped.Load() + logSink.discarded.Load(),
+		Length:   len(h.service.logChan),
+		Capacity: cap(h.service.logChan),
+		Alive:    loggerAlive.Load(),
+		Restarts: loggerRestarts.Load(),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// Обработчик GET /healthz
+// При deep=true дополнительно проверяется доступность хранилища
+func (h *TaskHandler) Health(w http.ResponseWriter, r *http.Request) {
+	status, code := map[string]string{"status": "ok"}, http.StatusOK
+	if deep, _ := strconv.ParseBool(r.URL.Query().Get("deep")); deep {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		if err := h.service.store.Ping(ctx); err != nil {
+			status = map[string]string{"status": "unavailable", "error": err.Error()}
+			code = http.StatusServiceUnavailable
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(status)
+}
+
+// Приёмник записей лога. После первой ошибки записи (например,
+// закрытый pipe) сообщает о ней один раз в stderr и дальше отбрасывает
+// записи, чтобы логгер продолжал вычитывать канал без повторных ошибок.
+type LogSink struct {
+	out       *log.Logger
+	errOut    io.Writer // Куда сообщается об ошибке записи (nil — stderr)
+	logfmt    bool      // Формат записей key=value (LOG_FORMAT=logfmt)
+	failed    atomic.Bool
+	written   atomic.Int64 // Записанные сообщения
+	discarded atomic.Int64 // Сообщения, отброшенные после ошибки записи
+}
+
+// Приёмник по умолчанию пишет через стандартный логгер
+var logSink = &LogSink{out: log.Default()}
+
+func (s *LogSink) Write(entry LogEntry) {
+	if s.failed.Load() {
+		s.discarded.Add(1)
+		return
+	}
+	var err error
+	if s.logfmt {
+		_, err = io.WriteString(s.out.Writer(), formatLogfmt(time.Now(), entry))
+	} else {
+		err = s.out.Output(2, formatLogText(entry))
+	}
+	if err == nil {
+		s.written.Add(1)
+	} else {
+		s.discarded.Add(1)
+	}
+	if err != nil && s.failed.CompareAndSwap(false, true) {
+		errOut := s.errOut
+		if errOut == nil {
+			errOut = os.Stderr
+		}
+		fmt.Fprintf(errOut, "Ошибка записи лога, дальнейшие сообщения отбрасываются: %v\n", err)
+	}
+}
+
+// Запись лога в текстовом формате: " [ЛОГ] WARN сообщение request_id=..."
+// (WARN — только у предупреждений, request_id — у записей запросов)
+func formatLogText(entry LogEntry) string {
+	line := " [ЛОГ] "
+	if entry.Level == levelWarn {
+		line += "WARN "
+	}
+	line += sanitizeLogEntry(entry.Message)
+	if entry.RequestID != "" {
+		line += " request_id=" + logfmtValue(entry.RequestID)
+	}
+	return line
+}
+
+// Запись лога в формате logfmt: ts=... level=info msg="..." request_id=...
+// (request_id — только у записей, сделанных при обработке запроса)
+func formatLogfmt(ts time.Time, entry LogEntry) string {
+	line := "ts=" + ts.UTC().Format(time.RFC3339Nano) + " level=" + entry.Level + " msg=" + logfmtValue(entry.Message)
+	if entry.RequestID != "" {
+		line += " request_id=" + logfmtValue(entry.RequestID)
+	}
+	return line + "\n"
+}
+
+// Значение logfmt; пустые значения и значения с пробелами, кавычками,
+// знаком = или управляющими символами заключаются в кавычки с экранированием
+func logfmtValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " =\"\\") || strings.ContainsFunc(value, isLogUnsafe) {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// Экранирование управляющих символов и разделителей строк, чтобы
+// данные клиента (например, название с переводом строки) не разрывали
+// и не подделывали строки лога
+func sanitizeLogEntry(entry string) string {
+	if !strings.ContainsFunc(entry, isLogUnsafe) {
+		return entry
+	}
+	var b strings.Builder
+	for _, r := range entry {
+		if isLogUnsafe(r) {
+			quoted := strconv.QuoteRune(r)
+			b.WriteString(quoted[1 : len(quoted)-1])
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func isLogUnsafe(r rune) bool {
+	return unicode.IsControl(r) || unicode.In(r, unicode.Zl, unicode.Zp)
+}
+
+// Запись сообщения в лог
+func writeLogEntry(entry LogEntry) {
+	logSink.Write(entry)
+}
+
+// Работает ли горутина асинхронного логгера
+var loggerAlive atomic.Bool
+
+// Сколько раз логгер перезапускался после паники при записи
+var loggerRestarts atomic.Int64
+
+// Асинхронный логгер. Паника при записи сообщения не останавливает
+// логирование: сообщение теряется, а чтение канала продолжается.
+func Logger(logChan <-chan LogEntry) {
+	loggerAlive.Store(true)
+	defer loggerAlive.Store(false)
+	for !drainLog(logChan) {
+		loggerRestarts.Add(1)
 	}
 	log.Println(" Логгер остановлен")
 }
 
-func main() {
-	// Инициализация компонентов
-	store := NewTaskStorage()
-	logChan := make(chan string, 100)
-	service := NewTaskService(store, logChan)
-	handler := NewTaskHandler(service)
-	// Запуск асинхронного логгера
-	go Logger(logChan)
+// Запись сообщений из канала до его закрытия; false — запись прервана паникой
+func drainLog(logChan <-chan LogEntry) (closed bool) {
+	defer func() {
+		if p := recover(); p != nil {
+			// Сообщение, на котором случилась паника, учитывается как отброшенное
+			logSink.discarded.Add(1)
+			fmt.Fprintf(os.Stderr, "Паника логгера, перезапуск: %v\n", p)
+		}
+	}()
+	for entry := range logChan {
+		writeLogEntry(entry)
+	}
+	return true
+}
+
+// Период проверки заполненности канала логов
+const logBackpressureInterval = time.Second
+
+// Наблюдение за заполненностью канала логов: раз в interval
+// проверяется len(logChan), и при достижении threshold процентов ёмкости
+// предупреждение пишется сразу в out (stderr), минуя сам канал
+func WatchLogBackpressure(logChan <-chan LogEntry, threshold int, interval time.Duration, out io.Writer, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if length, capacity := len(logChan), cap(logChan); capacity > 0 && length*100 >= capacity*threshold {
+				fmt.Fprintf(out, "%s WARN канал логов заполнен на %d%% (%d из %d)\n",
+					time.Now().Format("2006/01/02 15:04:05"), length*100/capacity, length, capacity)
+			}
+		}
+	}
+}
+
+// Пауза перед первой повторной попыткой занять адрес
+const listenRetryDelay = 500 * time.Millisecond
+
+// Открытие адреса сервера; если адрес занят, выполняется до retries
+// повторных попыток с удвоением паузы между ними. Если адрес так и
+// не освободился, ошибка подсказывает, что делать (errors.Is с
+// syscall.EADDRINUSE сохраняется).
+func listen(addr string, retries int) (net.Listener, error) {
+	delay := listenRetryDelay
+	for attempt := 0; ; attempt++ {
+		listener, err := net.Listen("tcp", addr)
+		if errors.Is(err, syscall.EADDRINUSE) && attempt >= retries {
+			return nil, fmt.Errorf("адрес %s уже занят: завершите другой процесс на этом порту или укажите свободный адрес в SERVER_ADDR: %w", addr, err)
+		}
+		if err == nil || !errors.Is(err, syscall.EADDRINUSE) {
+			return listener, err
+		}
+		log.Printf(" Адрес %s занят, повтор через %v", addr, delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// Маршруты API вместе с цепочкой промежуточных обработчиков
+func NewRouter(handler *TaskHandler, config Config) http.Handler {
 	// Настройка маршрутизатора
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /tasks", handler.GetTasks)
+	mux.HandleFunc("GET /tasks/count", handler.CountTasks)
+	mux.HandleFunc("GET /tasks/grouped", handler.GetGroupedTasks)
+	mux.HandleFunc("GET /tasks/export", handler.ExportTasks)
+	mux.HandleFunc("GET /tasks/snapshot", handler.CreateSnapshot)
 	mux.HandleFunc("GET /tasks/{id}", handler.GetTaskByID)
 	mux.HandleFunc("POST /tasks", handler.CreateTask)
+	mux.HandleFunc("PATCH /tasks/{id}", handler.PatchTask)
+	mux.HandleFunc(taskFallbackPattern, RejectUnsupportedMethod(mux))
+	mux.HandleFunc("POST /tasks/status", handler.GetStatuses)
+	mux.HandleFunc("POST /tasks/bulk-complete", handler.BulkComplete)
+	mux.HandleFunc("POST /tasks/{id}/progress", handler.AddProgress)
+	mux.HandleFunc("PUT /tasks/{id}/order", handler.SetOrder)
+	mux.HandleFunc("POST /tasks/import", handler.ImportTasks)
+	mux.HandleFunc("GET /healthz", handler.Health)
+	mux.HandleFunc("GET /version", handler.Version)
+	if config.AdminEndpoints {
+		mux.HandleFunc("GET /tasks/next-id", handler.GetNextID)
+		mux.HandleFunc("GET /debug/logger", handler.GetLoggerStats)
+	}
+	if config.EnableUI {
+		mux.HandleFunc("GET /ui", handler.TaskListPage)
+	}
+	// Цепочка промежуточных обработчиков
+	var root http.Handler = NegotiateContent(mux, config.FallbackToJSON)
+	root = WithRequestDecoding(root, config.MaxDecodedBody)
+	if config.VersionHeader {
+		root = WithVersionHeader(root)
+	}
+	if config.ServerTiming {
+		root = WithServerTiming(root)
+	}
+	root = WithRequestID(root, config.RequireRequestID)
+	return root
+}
+
+func main() {
+	// Загрузка и проверка настроек
+	config, warnings, err := LoadConfig(os.Environ())
+	for _, warning := range warnings {
+		log.Printf("Предупреждение конфигурации: %s", warning)
+	}
+	if err != nil {
+		log.Fatalf("Ошибки конфигурации:\n%v", err)
+	}
+	// Запись в закрытый pipe должна возвращать ошибку, а не завершать процесс:
+	// её обрабатывает приёмник лога
+	signal.Ignore(syscall.SIGPIPE)
+	// Инициализация компонентов
+	var store Storage = NewTaskStorage()
+	if config.StorageModel == "actor" {
+		store = NewActorStorage()
+	}
+	logChan := make(chan LogEntry, 100)
+	idempotency := NewIdempotencyStore(config.IdempotencyWait, config.IdempotencyTTL, config.IdempotencyKeys)
+	service := NewTaskService(store, logChan, idempotency, config)
+	handler := NewTaskHandler(service)
+	// Начальные данные для пустого хранилища
+	if config.SeedFile != "" {
+		seeded, err := service.Seed(config.SeedFile)
+		if err != nil {
+			log.Fatalf("Ошибка загрузки %s: %v", config.SeedFile, err)
+		}
+		log.Printf(" Загружено начальных задач из %s: %d", config.SeedFile, seeded)
+	}
+	// Запуск асинхронного логгера
+	logSink.logfmt = config.LogFormat == "logfmt"
+	loggerDone := make(chan struct{})
+	go func() {
+		Logger(logChan)
+		close(loggerDone)
+	}()
+	stopWatcher := make(chan struct{})
+	if config.LogHighWater > 0 {
+		go WatchLogBackpressure(logChan, config.LogHighWater, logBackpressureInterval, os.Stderr, stopWatcher)
+	}
+	root := NewRouter(handler, config)
 	// Конфигурация HTTP-сервера
+	listener, err := listen(config.ServerAddr, config.ListenRetries)
+	if err != nil {
+		log.Fatalf("Ошибка сервера: %v", err)
+	}
 	server := &http.Server{
-		Addr:    ":8080",
-		Handler: mux,
+		Addr:    config.ServerAddr,
+		Handler: root,
 	}
 	// Канал для сигналов ОС
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	// Запуск сервера в горутине
 	go func() {
-		log.Println(" Сервер запущен на http://localhost:8080")
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf(" Сервер запущен на %s", listener.Addr())
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Ошибка сервера: %v", err)
 		}
 	}()
@@ -193,6 +1368,10 @@ func main() {
 		log.Printf("Ошибка завершения: %v", err)
 	}
 	// Закрытие канала логов после завершения работы
-	close(logChan)
+	close(stopWatcher)
+	if dropped := service.CloseLog(); dropped > 0 {
+		log.Printf(" Отброшено сообщений лога при остановке: %d", dropped)
+	}
+	<-loggerDone
 	log.Println(" Сервер корректно остановлен")
 }
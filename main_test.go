@@ -0,0 +1,816 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Сервис с настройками по умолчанию, переопределёнными переменными
+// environ; канал логов вычитывается до завершения теста
+func newTestService(t testing.TB, environ ...string) *TaskService {
+	t.Helper()
+	config, _, err := LoadConfig(environ)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	var store Storage = NewTaskStorage()
+	if config.StorageModel == "actor" {
+		store = NewActorStorage()
+	}
+	logChan := make(chan LogEntry, 100)
+	service := NewTaskService(store, logChan, NewIdempotencyStore(config.IdempotencyWait, config.IdempotencyTTL, config.IdempotencyKeys), config)
+	done := make(chan struct{})
+	go func() {
+		for range logChan {
+		}
+		close(done)
+	}()
+	t.Cleanup(func() {
+		service.CloseLog()
+		<-done
+	})
+	return service
+}
+
+// Хранилища обеих моделей для тестов поведения Storage
+var storageModels = map[string]func() Storage{
+	"mutex": func() Storage { return NewTaskStorage() },
+	"actor": func() Storage { return NewActorStorage() },
+}
+
+// Проверка счётчиков Count по полному обходу задач
+func checkCounts(t *testing.T, store Storage) {
+	t.Helper()
+	all := store.GetAll(TaskFilter{})
+	completed := 0
+	for _, task := range all {
+		if task.Completed {
+			completed++
+		}
+	}
+	yes, no := true, false
+	if got := store.Count(nil); got != len(all) {
+		t.Errorf("Count(nil) = %d, scan = %d", got, len(all))
+	}
+	if got := store.Count(&yes); got != completed {
+		t.Errorf("Count(true) = %d, scan = %d", got, completed)
+	}
+	if got := store.Count(&no); got != len(all)-completed {
+		t.Errorf("Count(false) = %d, scan = %d", got, len(all)-completed)
+	}
+}
+
+func TestCountersMatchScanUnderConcurrentWrites(t *testing.T) {
+	for model, newStore := range storageModels {
+		t.Run(model, func(t *testing.T) {
+			store := newStore()
+			var wg sync.WaitGroup
+			for worker := range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := range 50 {
+						task := store.Create(Task{Title: "t", Completed: i%3 == 0})
+						store.Update(task.ID, func(task *Task) error {
+							task.Completed = !task.Completed
+							return nil
+						})
+						store.CompleteBatch([]VersionedID{{ID: task.ID, Version: 2}})
+						store.Import([]Task{{ID: task.ID, Title: "i", Completed: worker%2 == 0}}, ConflictOverwrite)
+						store.Count(nil)
+					}
+				}()
+			}
+			wg.Wait()
+			checkCounts(t, store)
+		})
+	}
+}
+
+// HTTP-запрос к маршрутам сервиса
+func serve(handler http.Handler, method, target, body string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestConcurrentProgressDeltas(t *testing.T) {
+	for model := range storageModels {
+		t.Run(model, func(t *testing.T) {
+			service := newTestService(t, "STORAGE_MODEL="+model)
+			router := NewRouter(NewTaskHandler(service), service.config)
+			task := service.store.Create(Task{Title: "t", Progress: 20})
+
+			// 50 приращений +1 и 20 уменьшений −1 от 20 не выходят за 0–100,
+			// поэтому итог не зависит от порядка: 20 + 50 − 20 = 50
+			target := "/tasks/" + strconv.Itoa(task.ID) + "/progress"
+			var wg sync.WaitGroup
+			for i := range 70 {
+				delta := `{"delta":1}`
+				if i < 20 {
+					delta = `{"delta":-1}`
+				}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if w := serve(router, "POST", target, delta); w.Code != http.StatusOK {
+						t.Errorf("POST %s: %d %s", target, w.Code, w.Body)
+					}
+				}()
+			}
+			wg.Wait()
+			got, _ := service.store.GetByID(task.ID)
+			if got.Progress != 50 {
+				t.Errorf("progress = %d, want 50", got.Progress)
+			}
+			if got.Version != 71 {
+				t.Errorf("version = %d, want 71", got.Version)
+			}
+		})
+	}
+}
+
+func TestProgressDeltaClamps(t *testing.T) {
+	service := newTestService(t)
+	router := NewRouter(NewTaskHandler(service), service.config)
+	task := service.store.Create(Task{Title: "t", Progress: 90})
+	target := "/tasks/" + strconv.Itoa(task.ID) + "/progress"
+	for _, tc := range []struct {
+		delta string
+		want  int
+	}{
+		{`{"delta":50}`, 100},
+		{`{"delta":-500}`, 0},
+		{`{"delta":1}`, 1},
+		{`{"delta":9223372036854775807}`, 100},
+		{`{"delta":-9223372036854775808}`, 0},
+		{`{"delta":1}`, 1},
+		{`{"delta":-9223372036854775807}`, 0},
+	} {
+		serve(router, "POST", target, tc.delta)
+		if got, _ := service.store.GetByID(task.ID); got.Progress != tc.want {
+			t.Errorf("after %s: progress = %d, want %d", tc.delta, got.Progress, tc.want)
+		}
+	}
+}
+
+// Удаления в API нет, поэтому нагрузка состоит из создания, изменений и чтений
+func TestStorageConcurrentStress(t *testing.T) {
+	for model, newStore := range storageModels {
+		t.Run(model, func(t *testing.T) {
+			store := newStore()
+			const workers, perWorker = 8, 100
+			var updates [workers * perWorker]atomic.Int32
+			var wg sync.WaitGroup
+			for range workers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					var own []int
+					for i := range perWorker {
+						own = append(own, store.Create(Task{Title: "t"}).ID)
+						id := own[i/2]
+						if _, err := store.Update(id, func(task *Task) error {
+							task.Progress = min(task.Progress+1, maxProgress)
+							return nil
+						}); err == nil {
+							updates[id-1].Add(1)
+						}
+						if task, ok := store.GetByID(id); !ok || task.ID != id {
+							t.Errorf("GetByID(%d) = %+v, %v", id, task, ok)
+						}
+						store.GetAll(TaskFilter{})
+						store.GroupBy(TaskFilter{}, func(task Task) string { return strconv.FormatBool(task.Completed) })
+						store.Statuses(own)
+						store.Count(nil)
+						store.Sequence()
+					}
+				}()
+			}
+			wg.Wait()
+
+			tasks := store.GetAll(TaskFilter{})
+			if len(tasks) != workers*perWorker {
+				t.Fatalf("tasks = %d, want %d", len(tasks), workers*perWorker)
+			}
+			seen := map[int]bool{}
+			for _, task := range tasks {
+				if seen[task.ID] || task.ID < 1 || task.ID > workers*perWorker {
+					t.Errorf("unexpected or duplicate ID %d", task.ID)
+				}
+				seen[task.ID] = true
+				if want := 1 + int(updates[task.ID-1].Load()); task.Version != want {
+					t.Errorf("task %d: version = %d, want %d", task.ID, task.Version, want)
+				}
+			}
+			if next := store.NextID(); next != workers*perWorker+1 {
+				t.Errorf("NextID = %d, want %d", next, workers*perWorker+1)
+			}
+			checkCounts(t, store)
+		})
+	}
+}
+
+func TestLogDuringShutdown(t *testing.T) {
+	config, _, _ := LoadConfig(nil)
+	logChan := make(chan LogEntry, 4)
+	service := NewTaskService(NewTaskStorage(), logChan, nil, config)
+	var received atomic.Int64
+	done := make(chan struct{})
+	go func() {
+		for range logChan {
+			received.Add(1)
+		}
+		close(done)
+	}()
+
+	const senders, perSender = 16, 200
+	var wg sync.WaitGroup
+	for range senders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perSender {
+				service.Log(context.Background(), "сообщение")
+			}
+		}()
+	}
+	// Остановка посреди отправок не должна приводить к панике
+	// из-за записи в закрытый канал, а каждое сообщение должно
+	// быть либо доставлено, либо учтено как отброшенное
+	time.Sleep(time.Millisecond)
+	service.CloseLog()
+	wg.Wait()
+	<-done
+	// CloseLog возвращает число отброшенных на момент закрытия,
+	// а отправители могли продолжать после него
+	dropped := service.dropped.Load()
+	if total := received.Load() + dropped; total != senders*perSender {
+		t.Errorf("received %d + dropped %d = %d, want %d", received.Load(), dropped, total, senders*perSender)
+	}
+	service.Log(context.Background(), "после остановки")
+	if service.dropped.Load() != dropped+1 {
+		t.Error("message after CloseLog was not counted as dropped")
+	}
+}
+
+func TestLogWarnBypassesSampling(t *testing.T) {
+	config, _, err := LoadConfig([]string{"LOG_SAMPLE_RATE=10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logChan := make(chan LogEntry, 100)
+	service := NewTaskService(NewTaskStorage(), logChan, nil, config)
+	for i := range 20 {
+		service.Log(context.Background(), "info "+strconv.Itoa(i))
+	}
+	for i := range 5 {
+		service.LogWarn(context.Background(), "warn "+strconv.Itoa(i))
+	}
+	service.CloseLog()
+	var infos, warns int
+	for entry := range logChan {
+		if entry.Level == levelWarn {
+			warns++
+		} else {
+			infos++
+		}
+	}
+	if infos != 2 || warns != 5 {
+		t.Fatalf("logged info=%d warn=%d, want 2 and 5", infos, warns)
+	}
+}
+
+// Приёмник лога, паникующий на первой записи
+type panicOnceWriter struct{ panicked atomic.Bool }
+
+func (w *panicOnceWriter) Write(p []byte) (int, error) {
+	if w.panicked.CompareAndSwap(false, true) {
+		panic("write failed")
+	}
+	return len(p), nil
+}
+
+func TestLoggerRestartsAfterPanic(t *testing.T) {
+	writer := &panicOnceWriter{}
+	swapLogSink(t, &LogSink{out: log.New(writer, "", 0)})
+	restarts := loggerRestarts.Load()
+
+	logChan := make(chan LogEntry, 3)
+	logChan <- LogEntry{Level: levelInfo, Message: "first"}
+	logChan <- LogEntry{Level: levelInfo, Message: "second"}
+	logChan <- LogEntry{Level: levelInfo, Message: "third"}
+	close(logChan)
+	Logger(logChan)
+
+	if got := loggerRestarts.Load() - restarts; got != 1 {
+		t.Errorf("restarts = %d, want 1", got)
+	}
+
+	// Первое сообщение потеряно при панике, остальные два записаны
+	service := newTestService(t, "ENABLE_ADMIN_ENDPOINTS=true")
+	w := serve(NewRouter(NewTaskHandler(service), service.config), "GET", "/debug/logger", "")
+	var stats LoggerStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("GET /debug/logger = %s: %v", w.Body, err)
+	}
+	if stats.Logged != 2 || stats.Dropped != 1 || stats.Restarts != loggerRestarts.Load() {
+		t.Errorf("stats = %+v, want logged 2, dropped 1, restarts %d", stats, loggerRestarts.Load())
+	}
+}
+
+func TestUnsupportedMethodsOnTask(t *testing.T) {
+	service := newTestService(t)
+	router := NewRouter(NewTaskHandler(service), service.config)
+	service.store.Create(Task{Title: "t"})
+	for _, method := range []string{"POST", "PUT", "DELETE"} {
+		w := serve(router, method, "/tasks/1", `{"title": "x"}`)
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s /tasks/1: status %d, want 405", method, w.Code)
+		}
+		if allow := w.Header().Get("Allow"); allow != "GET, HEAD, PATCH" {
+			t.Errorf("%s /tasks/1: Allow = %q, want %q", method, allow, "GET, HEAD, PATCH")
+		}
+		if !strings.Contains(w.Body.String(), "PATCH /tasks/{id}") {
+			t.Errorf("%s /tasks/1: body %s lacks the PATCH hint", method, w.Body)
+		}
+	}
+	// Соседние маршруты сохраняют свои методы в Allow
+	if w := serve(router, "DELETE", "/tasks/status", ""); w.Code != http.StatusMethodNotAllowed || !strings.Contains(w.Header().Get("Allow"), "POST") {
+		t.Errorf("DELETE /tasks/status: status %d, Allow %q, want 405 with POST", w.Code, w.Header().Get("Allow"))
+	}
+	if w := serve(router, "GET", "/tasks/1", ""); w.Code != http.StatusOK {
+		t.Errorf("GET /tasks/1: status %d, want 200", w.Code)
+	}
+}
+
+func TestNormalizeTitlesNFCAndWhitespace(t *testing.T) {
+	const decomposed = "  Cafe\u0301 \t  cre\u0300me\u3000 " // e + комбинируемые акценты, идеографический пробел
+	const want = "Caf\u00e9 cr\u00e8me"
+	for _, tt := range []struct {
+		environ []string
+		want    string
+	}{
+		{[]string{"NORMALIZE_TITLES=true"}, want},
+		{nil, decomposed},
+	} {
+		service := newTestService(t, tt.environ...)
+		router := NewRouter(NewTaskHandler(service), service.config)
+		body, _ := json.Marshal(map[string]string{"title": decomposed})
+		w := serve(router, "POST", "/tasks", string(body))
+		var created Task
+		if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+			t.Fatalf("POST /tasks: %v (%s)", err, w.Body)
+		}
+		if created.Title != tt.want {
+			t.Errorf("%v: title = %q, want %q", tt.environ, created.Title, tt.want)
+		}
+	}
+}
+
+func TestUnicodeTitlesRoundTrip(t *testing.T) {
+	// Длина названий считается в символах: 🎉 — 4 байта, но один символ
+	service := newTestService(t, "WARN_TITLE_LENGTH=2")
+	router := NewRouter(NewTaskHandler(service), service.config)
+	for _, title := range []string{"🎉", "👩\u200d💻🚀", "e\u0301", "\u0301"} {
+		body, _ := json.Marshal(map[string]string{"title": title})
+		w := serve(router, "POST", "/tasks", string(body))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("POST %q: status %d (%s)", title, w.Code, w.Body)
+		}
+		var created taskWithWarnings
+		if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+			t.Fatal(err)
+		}
+		if title == "🎉" && len(created.Warnings) != 0 {
+			t.Errorf("%q: warnings %q, want none for a single rune", title, created.Warnings)
+		}
+		w = serve(router, "GET", "/tasks/"+strconv.Itoa(created.ID), "")
+		if !strings.Contains(w.Body.String(), title) {
+			t.Errorf("GET %q: body %s does not contain the title bytes unchanged", title, w.Body)
+		}
+		var got Task
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || got.Title != title {
+			t.Errorf("GET %q: title %q (%v)", title, got.Title, err)
+		}
+	}
+}
+
+func TestGetStatuses(t *testing.T) {
+	service := newTestService(t)
+	router := NewRouter(NewTaskHandler(service), service.config)
+	service.store.Create(Task{Title: "a"})
+	done := service.store.Create(Task{Title: "b"})
+	service.store.Update(done.ID, func(task *Task) error {
+		task.Completed = true
+		return nil
+	})
+
+	w := serve(router, "POST", "/tasks/status", `{"ids": [1, 2, 7, 1]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d (%s)", w.Code, w.Body)
+	}
+	var got map[string]TaskStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]TaskStatus{"1": {false, 1}, "2": {true, 2}}
+	if len(got) != len(want) || got["1"] != want["1"] || got["2"] != want["2"] {
+		t.Errorf("statuses = %+v, want %+v (missing ID 7 omitted)", got, want)
+	}
+
+	for _, body := range []string{`{"ids": "1"}`, `{"ids": [1, "x"]}`, `{}`, `[1, 2]`, `{"ids": [1]`} {
+		if w := serve(router, "POST", "/tasks/status", body); w.Code != http.StatusBadRequest {
+			t.Errorf("body %s: status %d, want 400", body, w.Code)
+		}
+	}
+}
+
+func TestGetGroupedTasks(t *testing.T) {
+	for model := range storageModels {
+		t.Run(model, func(t *testing.T) {
+			service := newTestService(t, "STORAGE_MODEL="+model)
+			router := NewRouter(NewTaskHandler(service), service.config)
+			decode := func(target string) map[string][]Task {
+				t.Helper()
+				w := serve(router, "GET", target, "")
+				if w.Code != http.StatusOK {
+					t.Fatalf("%s: status %d (%s)", target, w.Code, w.Body)
+				}
+				var groups map[string][]Task
+				if err := json.Unmarshal(w.Body.Bytes(), &groups); err != nil {
+					t.Fatal(err)
+				}
+				return groups
+			}
+
+			// Пустое хранилище даёт пустой объект, а не null
+			if w := serve(router, "GET", "/tasks/grouped?by=completed", ""); strings.TrimSpace(w.Body.String()) != "{}" {
+				t.Errorf("empty store: body %q, want {}", w.Body)
+			}
+
+			service.store.Create(Task{Title: "a"})
+			service.store.Create(Task{Title: "b"})
+			done := service.store.Create(Task{Title: "c"})
+			service.store.Update(done.ID, func(task *Task) error {
+				task.Completed = true
+				return nil
+			})
+
+			groups := decode("/tasks/grouped?by=completed")
+			if len(groups) != 2 || len(groups["false"]) != 2 || len(groups["true"]) != 1 {
+				t.Errorf("groups = %+v, want 2 open and 1 completed", groups)
+			}
+			if tasks := groups["true"]; len(tasks) == 1 && tasks[0].ID != done.ID {
+				t.Errorf("completed group holds task %d, want %d", tasks[0].ID, done.ID)
+			}
+			// Фильтр применяется до группировки
+			if groups := decode("/tasks/grouped?by=completed&completed=true"); len(groups) != 1 || len(groups["true"]) != 1 {
+				t.Errorf("filtered groups = %+v, want only the completed group", groups)
+			}
+
+			if w := serve(router, "GET", "/tasks/grouped?by=title", ""); w.Code != http.StatusBadRequest {
+				t.Errorf("unsupported key: status %d, want 400", w.Code)
+			}
+		})
+	}
+}
+
+func TestNonUTCDueDateOverdue(t *testing.T) {
+	clock := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		due     string
+		utc     time.Time
+		overdue bool
+	}{
+		// По местному времени позже часов сервиса, но в UTC уже прошло
+		{"2030-01-01T02:00:00+03:00", time.Date(2029, 12, 31, 23, 0, 0, 0, time.UTC), true},
+		// По местному времени раньше часов сервиса, но в UTC ещё впереди
+		{"2029-12-31T22:00:00-03:00", time.Date(2030, 1, 1, 1, 0, 0, 0, time.UTC), false},
+	}
+	for model := range storageModels {
+		service := newTestService(t, "STORAGE_MODEL="+model)
+		service.now = func() time.Time { return clock }
+		router := NewRouter(NewTaskHandler(service), service.config)
+		for _, tt := range tests {
+			w := serve(router, "POST", "/tasks", `{"title": "t", "due_date": "`+tt.due+`"}`)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("%s %s: status %d (%s)", model, tt.due, w.Code, w.Body)
+			}
+			var created Task
+			json.Unmarshal(w.Body.Bytes(), &created)
+			if created.Overdue != tt.overdue {
+				t.Errorf("%s %s: overdue = %v, want %v", model, tt.due, created.Overdue, tt.overdue)
+			}
+			if created.DueDate == nil || !created.DueDate.Equal(tt.utc) || created.DueDate.Location() != time.UTC {
+				t.Errorf("%s %s: due_date = %v, want %v in UTC", model, tt.due, created.DueDate, tt.utc)
+			}
+		}
+	}
+}
+
+func TestTrackCompletion(t *testing.T) {
+	store := NewTaskStorage()
+	clock := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return clock }
+	setCompleted := func(id int, completed bool) Task {
+		t.Helper()
+		task, err := store.Update(id, func(task *Task) error {
+			task.Completed = completed
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return task
+	}
+
+	// Клиент не может задать completed_at при создании
+	task := store.Create(Task{Title: "a", CompletedAt: &clock})
+	if task.CompletedAt != nil {
+		t.Errorf("new task completed_at = %v, want nil", task.CompletedAt)
+	}
+
+	completedAt := clock
+	task = setCompleted(task.ID, true)
+	if task.CompletedAt == nil || !task.CompletedAt.Equal(completedAt) {
+		t.Errorf("false→true: completed_at = %v, want %v", task.CompletedAt, completedAt)
+	}
+	// Повторное завершение и правка других полей не сдвигают время
+	clock = clock.Add(time.Hour)
+	task = setCompleted(task.ID, true)
+	task, _ = store.Update(task.ID, func(task *Task) error {
+		task.Title = "b"
+		task.CompletedAt = nil
+		return nil
+	})
+	if task.CompletedAt == nil || !task.CompletedAt.Equal(completedAt) {
+		t.Errorf("true→true: completed_at = %v, want unchanged %v", task.CompletedAt, completedAt)
+	}
+
+	task = setCompleted(task.ID, false)
+	if task.CompletedAt != nil {
+		t.Errorf("true→false: completed_at = %v, want nil", task.CompletedAt)
+	}
+	task = setCompleted(task.ID, false)
+	if task.CompletedAt != nil {
+		t.Errorf("false→false: completed_at = %v, want nil", task.CompletedAt)
+	}
+}
+
+func TestParseTaskID(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int // 0 — ожидается ErrInvalidID
+	}{
+		{"42", 42},
+		{" 42\t", 42}, // Пробелы по краям отбрасываются
+		{"042", 0},
+		{"0", 0},
+		{"+42", 0},
+		{"-42", 0},
+		{"", 0},
+		{"   ", 0},
+		{"4 2", 0},
+		{"42abc", 0},
+		{"99999999999999999999", 0},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/tasks/x", nil)
+		r.SetPathValue("id", tt.value)
+		id, err := parseTaskID(r)
+		if tt.want == 0 {
+			if !errors.Is(err, ErrInvalidID) {
+				t.Errorf("%q: got %d, %v; want ErrInvalidID", tt.value, id, err)
+			}
+		} else if err != nil || id != tt.want {
+			t.Errorf("%q: got %d, %v; want %d", tt.value, id, err, tt.want)
+		}
+	}
+
+	service := newTestService(t)
+	router := NewRouter(NewTaskHandler(service), service.config)
+	service.store.Create(Task{Title: "a"})
+	for target, want := range map[string]int{"/tasks/1": 200, "/tasks/%201": 200, "/tasks/01": 400, "/tasks/+1": 400} {
+		if w := serve(router, "GET", target, ""); w.Code != want {
+			t.Errorf("GET %s: status %d, want %d", target, w.Code, want)
+		}
+	}
+}
+
+func TestBulkCompleteMixedVersions(t *testing.T) {
+	for model := range storageModels {
+		t.Run(model, func(t *testing.T) {
+			service := newTestService(t, "STORAGE_MODEL="+model)
+			router := NewRouter(NewTaskHandler(service), service.config)
+			service.store.Create(Task{Title: "a"})
+			service.store.Create(Task{Title: "b"})
+			service.store.Update(2, func(task *Task) error { // Версия 2
+				task.Title = "b2"
+				return nil
+			})
+			service.store.Create(Task{Title: "c"})
+
+			w := serve(router, "POST", "/tasks/bulk-complete", `{"items": [
+				{"id": 1, "version": 1},
+				{"id": 2, "version": 1},
+				{"id": 3, "version": 1},
+				{"id": 9, "version": 1}
+			]}`)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d (%s)", w.Code, w.Body)
+			}
+			var response struct{ Results []BulkCompleteResult }
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			want := []BulkCompleteResult{
+				{ID: 1, Status: BulkUpdated, Version: 2},
+				{ID: 2, Status: BulkVersionMismatch, Version: 2},
+				{ID: 3, Status: BulkUpdated, Version: 2},
+				{ID: 9, Status: BulkNotFound},
+			}
+			if len(response.Results) != len(want) {
+				t.Fatalf("results = %+v, want %+v", response.Results, want)
+			}
+			for i, result := range response.Results {
+				if result != want[i] {
+					t.Errorf("result %d = %+v, want %+v", i, result, want[i])
+				}
+			}
+			// Задача с устаревшей версией не изменилась, остальные завершены
+			for id, completed := range map[int]bool{1: true, 2: false, 3: true} {
+				task, _ := service.store.GetByID(id)
+				if task.Completed != completed || (task.CompletedAt != nil) != completed {
+					t.Errorf("task %d: completed %v, completed_at %v; want completed %v", id, task.Completed, task.CompletedAt, completed)
+				}
+			}
+			checkCounts(t, service.store)
+		})
+	}
+}
+
+func TestGetNextIDMatchesCreatedID(t *testing.T) {
+	for model := range storageModels {
+		t.Run(model, func(t *testing.T) {
+			service := newTestService(t, "STORAGE_MODEL="+model, "ENABLE_ADMIN_ENDPOINTS=true")
+			router := NewRouter(NewTaskHandler(service), service.config)
+			nextID := func() int {
+				t.Helper()
+				var response struct {
+					NextID int `json:"next_id"`
+				}
+				w := serve(router, "GET", "/tasks/next-id", "")
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+					t.Fatalf("next-id: status %d, %s", w.Code, w.Body)
+				}
+				return response.NextID
+			}
+			create := func() int {
+				t.Helper()
+				var task Task
+				json.Unmarshal(serve(router, "POST", "/tasks", `{"title": "t"}`).Body.Bytes(), &task)
+				return task.ID
+			}
+
+			for range 3 {
+				if want, got := nextID(), create(); got != want {
+					t.Errorf("created ID %d, next-id promised %d", got, want)
+				}
+			}
+			// Импорт с явным ID сдвигает следующий ID за него
+			serve(router, "POST", "/tasks/import", `[{"id": 10, "title": "i"}]`)
+			if want, got := nextID(), create(); want != 11 || got != want {
+				t.Errorf("after importing ID 10: next-id %d, created %d; want 11", want, got)
+			}
+		})
+	}
+	service := newTestService(t)
+	if w := serve(NewRouter(NewTaskHandler(service), service.config), "GET", "/tasks/next-id", ""); w.Code == http.StatusOK {
+		t.Error("next-id is served without ENABLE_ADMIN_ENDPOINTS")
+	}
+}
+
+func TestDecodeJSONStrict(t *testing.T) {
+	tests := []struct {
+		body     string
+		strictOK bool
+	}{
+		{`{"title": "a"}`, true},
+		{"{\"title\": \"a\"}\n\t ", true}, // Пробельные символы после значения допустимы
+		{`{"title": "a"}garbage`, false},
+		{`{"title": "a"}{"title": "b"}`, false},
+		{`{"title": "a"} {"title": "b"}`, false},
+		{`{"title": "a"}]`, false},
+	}
+	for _, tt := range tests {
+		for _, strict := range []bool{true, false} {
+			var v struct{ Title string }
+			err := decodeJSON(strings.NewReader(tt.body), &v, strict)
+			if strict && !tt.strictOK {
+				if !errors.Is(err, ErrTrailingData) {
+					t.Errorf("strict %q: err = %v, want ErrTrailingData", tt.body, err)
+				}
+				continue
+			}
+			// Без строгого режима читается только первое значение
+			if err != nil || v.Title != "a" {
+				t.Errorf("strict=%v %q: title %q, err %v", strict, tt.body, v.Title, err)
+			}
+		}
+	}
+
+	for mode, want := range map[string]int{"true": http.StatusBadRequest, "false": http.StatusCreated} {
+		service := newTestService(t, "STRICT_JSON="+mode)
+		router := NewRouter(NewTaskHandler(service), service.config)
+		if w := serve(router, "POST", "/tasks", `{"title": "a"}{"title": "b"}`); w.Code != want {
+			t.Errorf("STRICT_JSON=%s: status %d, want %d", mode, w.Code, want)
+		}
+	}
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	buf := bufferLogSink(t)
+	service := newTestService(t, "MAX_RESPONSE_BYTES=1000", "SYNC_LOGGING=true")
+	router := NewRouter(NewTaskHandler(service), service.config)
+	for range 3 {
+		service.store.Create(Task{Title: "t"})
+	}
+	small := serve(router, "GET", "/tasks", "")
+	if small.Code != http.StatusOK || small.Body.Len() > 1000 {
+		t.Fatalf("small list: status %d, %d bytes", small.Code, small.Body.Len())
+	}
+	ids := []string{}
+	for range 40 {
+		task := service.store.Create(Task{Title: strings.Repeat("x", 50)})
+		ids = append(ids, strconv.Itoa(task.ID))
+	}
+	for _, tt := range []struct{ method, target, body string }{
+		{"GET", "/tasks", ""},
+		{"GET", "/tasks/grouped?by=completed", ""},
+		{"POST", "/tasks/status", `{"ids": [` + strings.Join(ids, ",") + `]}`},
+	} {
+		w := serve(router, tt.method, tt.target, tt.body)
+		if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), `"title"`) {
+			t.Errorf("%s %s: status %d, %d bytes; want 500 without the list", tt.method, tt.target, w.Code, w.Body.Len())
+		}
+	}
+	if !strings.Contains(buf.String(), "WARN Ответ отклонён") || !strings.Contains(buf.String(), "превышает MAX_RESPONSE_BYTES") {
+		t.Errorf("log %q, want a warning about MAX_RESPONSE_BYTES", buf.String())
+	}
+	// Одиночная задача и выгрузка потоком под этот лимит не попадают
+	if w := serve(router, "GET", "/tasks/1", ""); w.Code != http.StatusOK {
+		t.Errorf("single task: status %d, want 200", w.Code)
+	}
+	if w := serve(router, "GET", "/tasks/export", ""); w.Code != http.StatusOK || w.Body.Len() <= 1000 {
+		t.Errorf("export: status %d, %d bytes; want a full streamed export", w.Code, w.Body.Len())
+	}
+}
+
+func TestDoneTitleMarker(t *testing.T) {
+	tests := []struct {
+		environ   []string
+		title     string
+		wantTitle string
+		completed bool
+	}{
+		{[]string{"DONE_TITLE_PREFIX=[x] "}, "[x] Купить молоко", "Купить молоко", true},
+		{[]string{"DONE_TITLE_PREFIX=[x] "}, "Купить молоко", "Купить молоко", false},
+		{[]string{"DONE_TITLE_PREFIX=[x] "}, "Купить [x] молоко", "Купить [x] молоко", false},
+		{[]string{"DONE_TITLE_PREFIX=[x] "}, "[X] Купить молоко", "[X] Купить молоко", false},
+		{nil, "[x] Купить молоко", "[x] Купить молоко", false}, // Без настройки префикс не распознаётся
+	}
+	for _, tt := range tests {
+		service := newTestService(t, tt.environ...)
+		router := NewRouter(NewTaskHandler(service), service.config)
+		w := serve(router, "POST", "/tasks", `{"title": `+strconv.Quote(tt.title)+`}`)
+		var created Task
+		json.Unmarshal(w.Body.Bytes(), &created)
+		if w.Code != http.StatusCreated || created.Title != tt.wantTitle || created.Completed != tt.completed {
+			t.Errorf("%v %q: status %d, title %q, completed %v; want %q, %v", tt.environ, tt.title, w.Code, created.Title, created.Completed, tt.wantTitle, tt.completed)
+		}
+		if (created.CompletedAt != nil) != tt.completed {
+			t.Errorf("%v %q: completed_at = %v", tt.environ, tt.title, created.CompletedAt)
+		}
+	}
+
+	// Название из одной отметки после её удаления пустое
+	service := newTestService(t, "DONE_TITLE_PREFIX=[x] ")
+	if w := serve(NewRouter(NewTaskHandler(service), service.config), "POST", "/tasks", `{"title": "[x] "}`); w.Code != http.StatusBadRequest {
+		t.Errorf("marker-only title: status %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Типы содержимого, которые умеет отдавать сервер
+var supportedMediaTypes = []string{"application/json"}
+
+// Маршруты, отдающие не JSON
+var routeMediaTypes = map[string][]string{
+	"/ui": {"text/html"},
+}
+
+// Типы содержимого, которые отдаёт маршрут path
+func mediaTypesFor(path string) []string {
+	if types, ok := routeMediaTypes[path]; ok {
+		return types
+	}
+	return supportedMediaTypes
+}
+
+// Проверка заголовка Accept: при отсутствии подходящего типа
+// запрос либо отклоняется с 406, либо обслуживается в формате маршрута
+// (FALLBACK_TO_JSON)
+func NegotiateContent(next http.Handler, fallbackToJSON bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		supported := mediaTypesFor(r.URL.Path)
+		if accept := r.Header.Get("Accept"); accept != "" && !fallbackToJSON && negotiateMediaType(accept, supported) == "" {
+			http.Error(w, "Поддерживаемые типы: "+strings.Join(supported, ", "), http.StatusNotAcceptable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Выбор поддерживаемого типа по заголовку Accept, пустая строка — совпадений нет
+func negotiateMediaType(accept string, supportedTypes []string) string {
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+			continue
+		}
+		for _, supported := range supportedTypes {
+			if mediaRangeMatches(mediaRange, supported) {
+				return supported
+			}
+		}
+	}
+	return ""
+}
+
+// Соответствие типа диапазону вида type/subtype, type/* или */*
+func mediaRangeMatches(mediaRange, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(mediaRange, "/*")
+	return ok && strings.HasPrefix(mediaType, prefix+"/")
+}
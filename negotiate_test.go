@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNegotiatePerRoute(t *testing.T) {
+	tests := []struct {
+		target, accept string
+		want           int // При FALLBACK_TO_JSON=false
+		contentType    string
+	}{
+		{"/ui", "text/html", http.StatusOK, "text/html"},
+		{"/ui", "text/*;q=0.9, application/json", http.StatusOK, "text/html"},
+		{"/ui", "application/json", http.StatusNotAcceptable, "text/html"},
+		{"/tasks", "application/json", http.StatusOK, "application/json"},
+		{"/tasks", "text/html", http.StatusNotAcceptable, "application/json"},
+	}
+	for _, fallback := range []string{"false", "true"} {
+		service := newTestService(t, "FALLBACK_TO_JSON="+fallback, "ENABLE_UI=true")
+		router := NewRouter(NewTaskHandler(service), service.config)
+		for _, tt := range tests {
+			want := tt.want
+			if fallback == "true" {
+				// Неподходящий Accept не отклоняется: ответ в формате маршрута
+				want = http.StatusOK
+			}
+			w := serve(router, "GET", tt.target, "", "Accept", tt.accept)
+			if w.Code != want {
+				t.Errorf("FALLBACK_TO_JSON=%s GET %s Accept %q: status %d, want %d", fallback, tt.target, tt.accept, w.Code, want)
+			}
+			if got := w.Header().Get("Content-Type"); want == http.StatusOK && !strings.HasPrefix(got, tt.contentType) {
+				t.Errorf("FALLBACK_TO_JSON=%s GET %s Accept %q: Content-Type %q, want %s", fallback, tt.target, tt.accept, got, tt.contentType)
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// Шаг порядка между соседними задачами: новая задача встаёт в конец
+// с шагом orderGap, а при перемещении занимает середину промежутка
+// между соседями, поэтому остальные задачи не перенумеровываются.
+// Нулевой порядок означает «не задан» (например, при импорте).
+const orderGap = 1024
+
+// Ошибки перемещения задачи
+var (
+	ErrOrderAnchorNotFound = errors.New("задача, относительно которой выполняется перемещение, не найдена")
+	ErrOrderAnchorSelf     = errors.New("задачу нельзя переместить относительно самой себя")
+)
+
+// Перемещение задачи id непосредственно перед (before) или после задачи anchor.
+// Если промежуток между соседями исчерпан (середина совпала с границей
+// из-за точности float64), все задачи перенумеровываются с шагом orderGap.
+func (s *TaskStorage) Reorder(id, anchor int, before bool) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.tasks[id]; !exists {
+		return Task{}, ErrTaskNotFound
+	}
+	if _, exists := s.tasks[anchor]; !exists {
+		return Task{}, ErrOrderAnchorNotFound
+	}
+	if id == anchor {
+		return Task{}, ErrOrderAnchorSelf
+	}
+	order, ok := s.orderNear(id, anchor, before)
+	if !ok {
+		s.rebalance()
+		order, _ = s.orderNear(id, anchor, before)
+	}
+	task := s.tasks[id]
+	task.Order = order
+	task.Version++
+	s.put(task)
+	return s.tasks[id], nil
+}
+
+// Порядок для вставки рядом с anchor без учёта перемещаемой задачи;
+// false — между anchor и соседом не осталось промежуточных значений
+func (s *TaskStorage) orderNear(id, anchor int, before bool) (float64, bool) {
+	tasks := s.ordered()
+	tasks = slices.DeleteFunc(tasks, func(task Task) bool { return task.ID == id })
+	i := slices.IndexFunc(tasks, func(task Task) bool { return task.ID == anchor })
+	current := tasks[i].Order
+	// Порядок всегда положителен: перед первой задачей границей служит 0
+	var neighbor float64
+	switch {
+	case before && i == 0:
+	case before:
+		neighbor = tasks[i-1].Order
+	case i == len(tasks)-1:
+		return current + orderGap, true
+	default:
+		neighbor = tasks[i+1].Order
+	}
+	middle := current + (neighbor-current)/2
+	return middle, middle != current && middle != neighbor
+}
+
+// Перенумерация всех задач с шагом orderGap с сохранением текущего порядка.
+// Версии не меняются: взаимный порядок задач остаётся прежним, и
+// перемещение одной задачи не должно ломать проверки версий у клиентов
+// (bulk-complete, JSON Patch test /version) для всех остальных.
+func (s *TaskStorage) rebalance() {
+	for i, task := range s.ordered() {
+		task.Order = float64(i+1) * orderGap
+		s.put(task)
+	}
+}
+
+// Задачи в порядке поля order
+func (s *TaskStorage) ordered() []Task {
+	tasks := make([]Task, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		tasks = append(tasks, task)
+	}
+	sortTasks(tasks, "order", sortAsc)
+	return tasks
+}
+
+func (s *ActorStorage) Reorder(id, anchor int, before bool) (Task, error) {
+	var (
+		updated Task
+		err     error
+	)
+	s.exec(func(state *TaskStorage) { updated, err = state.Reorder(id, anchor, before) })
+	return updated, err
+}
+
+// Обработчик PUT /tasks/{id}/order
+// Тело {"before": 3} или {"after": 3} ставит задачу перед или после задачи #3
+func (h *TaskHandler) SetOrder(w http.ResponseWriter, r *http.Request) {
+	// Парсинг ID из URL
+	id, err := parseTaskID(r)
+	if err != nil {
+		http.Error(w, "Некорректный ID", http.StatusBadRequest)
+		return
+	}
+	// Декодирование тела запроса: ровно одно из before и after
+	var request struct {
+		Before *int `json:"before"`
+		After  *int `json:"after"`
+	}
+	if err := decodeJSON(r.Body, &request, h.service.config.StrictJSON); err != nil || (request.Before == nil) == (request.After == nil) {
+		writeDecodeError(w, err, "Неверный формат данных: укажите before или after")
+		return
+	}
+	anchor, before := 0, request.Before != nil
+	if before {
+		anchor = *request.Before
+	} else {
+		anchor = *request.After
+	}
+	// Перемещение задачи
+	updatedTask, err := h.service.store.Reorder(id, anchor, before)
+	switch {
+	case errors.Is(err, ErrTaskNotFound):
+		http.Error(w, "Задача не найдена", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Асинхронное логирование
+	h.service.Log(r.Context(), "Задача #"+strconv.Itoa(id)+" перемещена рядом с задачей #"+strconv.Itoa(anchor))
+	// Формирование ответа
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.service.present(updatedTask))
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// ID задач в порядке поля order
+func orderedIDs(store *TaskStorage) []int {
+	var ids []int
+	for _, task := range store.ordered() {
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+func TestReorderInsertsBetweenNeighbours(t *testing.T) {
+	service := newTestService(t)
+	router := NewRouter(NewTaskHandler(service), service.config)
+	for _, title := range []string{"a", "b", "c", "d"} {
+		service.store.Create(Task{Title: title})
+	}
+	before, _ := service.store.GetByID(2)
+
+	// d между a и b, затем c перед a
+	for _, move := range []struct{ target, body string }{
+		{"/tasks/4/order", `{"after": 1}`},
+		{"/tasks/3/order", `{"before": 1}`},
+	} {
+		if w := serve(router, "PUT", move.target, move.body); w.Code != 200 {
+			t.Fatalf("PUT %s %s: status %d (%s)", move.target, move.body, w.Code, w.Body)
+		}
+	}
+	moved, _ := service.store.GetByID(4)
+	if moved.Order <= orderGap || moved.Order >= 2*orderGap {
+		t.Errorf("order of task inserted between 1024 and 2048 = %v", moved.Order)
+	}
+	if after, _ := service.store.GetByID(2); after.Order != before.Order || after.Version != before.Version {
+		t.Errorf("neighbour changed: %+v -> %+v", before, after)
+	}
+
+	w := serve(router, "GET", "/tasks?sort=order", "")
+	var tasks []Task
+	if err := json.Unmarshal(w.Body.Bytes(), &tasks); err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	if want := []int{3, 1, 4, 2}; !slices.Equal(ids, want) {
+		t.Errorf("GET /tasks?sort=order = %v, want %v", ids, want)
+	}
+
+	for _, bad := range []struct{ target, body string }{
+		{"/tasks/1/order", `{"after": 1}`},
+		{"/tasks/1/order", `{"after": 99}`},
+		{"/tasks/1/order", `{"before": 2, "after": 3}`},
+		{"/tasks/1/order", `{}`},
+	} {
+		if w := serve(router, "PUT", bad.target, bad.body); w.Code != 400 {
+			t.Errorf("PUT %s %s: status %d, want 400", bad.target, bad.body, w.Code)
+		}
+	}
+	if w := serve(router, "PUT", "/tasks/99/order", `{"after": 1}`); w.Code != 404 {
+		t.Errorf("PUT /tasks/99/order: status %d, want 404", w.Code)
+	}
+}
+
+func TestReorderRebalancesWhenGapIsExhausted(t *testing.T) {
+	store := NewTaskStorage()
+	for _, title := range []string{"a", "b", "c"} {
+		store.Create(Task{Title: title})
+	}
+	// Между a и b не осталось промежуточных значений float64
+	a, b := store.tasks[1], store.tasks[2]
+	b.Order = math.Nextafter(a.Order, math.Inf(1))
+	store.tasks[2] = b
+	versions := map[int]int{}
+	for id, task := range store.tasks {
+		versions[id] = task.Version
+	}
+
+	moved, err := store.Reorder(3, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 3, 2}; !slices.Equal(orderedIDs(store), want) {
+		t.Fatalf("order after rebalance = %v, want %v", orderedIDs(store), want)
+	}
+	for _, task := range store.ordered() {
+		if math.Mod(task.Order, orderGap) != 0 && task.ID != moved.ID {
+			t.Errorf("task %d order %v is not on the %d grid after rebalance", task.ID, task.Order, orderGap)
+		}
+	}
+	// Перенумерация не меняет версии задач, которые не перемещались
+	for _, id := range []int{1, 2} {
+		if got := store.tasks[id].Version; got != versions[id] {
+			t.Errorf("task %d version %d, want %d unchanged", id, got, versions[id])
+		}
+	}
+	if moved.Version != versions[3]+1 {
+		t.Errorf("moved task version %d, want %d", moved.Version, versions[3]+1)
+	}
+}
+
+func TestReorderRepeatedInsertsStayOrdered(t *testing.T) {
+	for model, newStore := range storageModels {
+		t.Run(model, func(t *testing.T) {
+			store := newStore()
+			for i := range 3 {
+				store.Create(Task{Title: strconv.Itoa(i)})
+			}
+			// Каждое перемещение делит промежуток после задачи 1 пополам;
+			// за 200 шагов он исчерпывается многократно
+			for i := range 200 {
+				id, other := 2+i%2, 3-i%2
+				if _, err := store.Reorder(id, 1, false); err != nil {
+					t.Fatal(err)
+				}
+				tasks := store.GetAll(TaskFilter{})
+				sortTasks(tasks, "order", sortAsc)
+				if got := []int{tasks[0].ID, tasks[1].ID, tasks[2].ID}; !slices.Equal(got, []int{1, id, other}) {
+					t.Fatalf("step %d: order %v, want %v", i, got, []int{1, id, other})
+				}
+			}
+		})
+	}
+}
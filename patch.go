@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// Ошибки применения JSON Patch
+var (
+	ErrPatchInvalid    = errors.New("некорректный JSON Patch")
+	ErrPatchImmutable  = errors.New("поле нельзя изменить")
+	ErrPatchTestFailed = errors.New("проверка test не пройдена")
+)
+
+// Операция JSON Patch (RFC 6902)
+type PatchOperation struct {
+	Op    string          `json:"op"`    // Тип операции: add, remove, replace, test
+	Path  string          `json:"path"`  // JSON Pointer на поле задачи
+	Value json.RawMessage `json:"value"` // Значение для add, replace и test
+}
+
+// Изменяет ли патч название (операции test не считаются)
+func patchesTitle(ops []PatchOperation) bool {
+	return slices.ContainsFunc(ops, func(op PatchOperation) bool {
+		return op.Path == "/title" && op.Op != "test"
+	})
+}
+
+// Разбор списка операций с проверкой типов и путей
+func ParsePatch(r io.Reader, strict bool) ([]PatchOperation, error) {
+	var ops []PatchOperation
+	if err := decodeJSON(r, &ops, strict); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPatchInvalid, err)
+	}
+	for _, op := range ops {
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return nil, fmt.Errorf("%w: для %s требуется value", ErrPatchInvalid, op.Op)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("%w: неподдерживаемая операция %q", ErrPatchInvalid, op.Op)
+		}
+		switch op.Path {
+		case "/title", "/completed", "/due_date", "/progress":
+		case "/id", "/created_at", "/version", "/completed_at":
+			// test ничего не меняет и позволяет проверить версию перед изменением
+			if op.Op != "test" {
+				return nil, fmt.Errorf("%w: %s", ErrPatchImmutable, op.Path)
+			}
+		default:
+			return nil, fmt.Errorf("%w: неизвестный путь %q", ErrPatchInvalid, op.Path)
+		}
+	}
+	return ops, nil
+}
+
+// Последовательное применение операций к задаче.
+// При ошибке задача может остаться частично изменённой,
+// поэтому вызывающий код применяет патч к копии.
+// Для неизменяемых полей ParsePatch пропускает только test.
+func ApplyPatch(task *Task, ops []PatchOperation) error {
+	for _, op := range ops {
+		var err error
+		switch op.Path {
+		case "/title":
+			err = applyPatchField(&task.Title, op)
+		case "/completed":
+			err = applyPatchField(&task.Completed, op)
+		case "/due_date":
+			err = applyPatchDueDate(&task.DueDate, op)
+		case "/progress":
+			err = applyPatchField(&task.Progress, op)
+		case "/id":
+			err = applyPatchField(&task.ID, op)
+		case "/version":
+			err = applyPatchField(&task.Version, op)
+		case "/created_at":
+			createdAt := &task.CreatedAt
+			err = applyPatchDueDate(&createdAt, op)
+		case "/completed_at":
+			err = applyPatchDueDate(&task.CompletedAt, op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Применение одной операции к полю задачи.
+// Поля задачи существуют всегда, поэтому add ведёт себя как replace,
+// а remove сбрасывает поле в нулевое значение. Значение null для
+// обязательных полей отклоняется: json.Unmarshal оставил бы поле как есть.
+func applyPatchField[T comparable](field *T, op PatchOperation) error {
+	if op.Op != "remove" && isJSONNull(op.Value) {
+		return fmt.Errorf("%w: null недопустим для %s", ErrPatchInvalid, op.Path)
+	}
+	switch op.Op {
+	case "add", "replace":
+		var value T
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return fmt.Errorf("%w: неверное значение для %s", ErrPatchInvalid, op.Path)
+		}
+		*field = value
+	case "remove":
+		var zero T
+		*field = zero
+	case "test":
+		var value T
+		if err := json.Unmarshal(op.Value, &value); err != nil || value != *field {
+			return fmt.Errorf("%w: %s", ErrPatchTestFailed, op.Path)
+		}
+	}
+	return nil
+}
+
+// Применение операции к необязательному моменту времени (срок выполнения;
+// для created_at и completed_at — только test):
+// remove и add/replace со значением null удаляют срок,
+// test сравнивает моменты времени
+func applyPatchDueDate(field **time.Time, op PatchOperation) error {
+	switch op.Op {
+	case "add", "replace":
+		var value *time.Time
+		if err := json.Unmarshal(op.Value, &value); err != nil || (value != nil && value.IsZero()) {
+			return fmt.Errorf("%w: неверное значение для %s", ErrPatchInvalid, op.Path)
+		}
+		*field = value
+	case "remove":
+		*field = nil
+	case "test":
+		var value *time.Time
+		if err := json.Unmarshal(op.Value, &value); err != nil || !equalTimes(value, *field) {
+			return fmt.Errorf("%w: %s", ErrPatchTestFailed, op.Path)
+		}
+	}
+	return nil
+}
+
+// Значение операции — JSON null
+func isJSONNull(value json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(value), []byte("null"))
+}
+
+// Сравнение необязательных моментов времени
+func equalTimes(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustParsePatch(t *testing.T, body string) []PatchOperation {
+	t.Helper()
+	ops, err := ParsePatch(strings.NewReader(body), true)
+	if err != nil {
+		t.Fatalf("ParsePatch(%s): %v", body, err)
+	}
+	return ops
+}
+
+func TestApplyPatchNullDueDateClears(t *testing.T) {
+	for _, op := range []string{"add", "replace"} {
+		due := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		task := Task{Title: "a", DueDate: &due}
+		ops := mustParsePatch(t, `[{"op":"`+op+`","path":"/due_date","value":null}]`)
+		if err := ApplyPatch(&task, ops); err != nil {
+			t.Fatalf("%s null: %v", op, err)
+		}
+		if task.DueDate != nil {
+			t.Errorf("%s null: due_date = %v, want nil", op, task.DueDate)
+		}
+	}
+}
+
+func TestApplyPatchRejectsNullAndZeroValues(t *testing.T) {
+	tests := []string{
+		`[{"op":"replace","path":"/title","value":null}]`,
+		`[{"op":"add","path":"/progress","value":null}]`,
+		`[{"op":"replace","path":"/completed","value":null}]`,
+		`[{"op":"test","path":"/progress","value":null}]`,
+		`[{"op":"replace","path":"/due_date","value":"0001-01-01T00:00:00Z"}]`,
+	}
+	for _, body := range tests {
+		task := Task{Title: "a", Progress: 0}
+		err := ApplyPatch(&task, mustParsePatch(t, body))
+		if !errors.Is(err, ErrPatchInvalid) {
+			t.Errorf("%s: err = %v, want ErrPatchInvalid", body, err)
+		}
+		if task.Title != "a" || task.DueDate != nil {
+			t.Errorf("%s: task changed to %+v", body, task)
+		}
+	}
+}
+
+func TestPatchTestOnImmutablePaths(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	task := Task{ID: 7, Title: "a", Version: 3, CreatedAt: created}
+	ops := mustParsePatch(t, `[
+		{"op":"test","path":"/id","value":7},
+		{"op":"test","path":"/version","value":3},
+		{"op":"test","path":"/created_at","value":"2026-01-02T03:04:05Z"},
+		{"op":"test","path":"/completed_at","value":null},
+		{"op":"replace","path":"/title","value":"b"}
+	]`)
+	if err := ApplyPatch(&task, ops); err != nil {
+		t.Fatalf("ApplyPatch: %v", err)
+	}
+	if task.Title != "b" {
+		t.Errorf("title = %q, want b", task.Title)
+	}
+
+	stale := mustParsePatch(t, `[{"op":"test","path":"/version","value":2}]`)
+	if err := ApplyPatch(&task, stale); !errors.Is(err, ErrPatchTestFailed) {
+		t.Errorf("stale version: err = %v, want ErrPatchTestFailed", err)
+	}
+
+	for _, op := range []string{"add", "replace", "remove"} {
+		body := `[{"op":"` + op + `","path":"/version","value":1}]`
+		if _, err := ParsePatch(strings.NewReader(body), true); !errors.Is(err, ErrPatchImmutable) {
+			t.Errorf("%s /version: err = %v, want ErrPatchImmutable", op, err)
+		}
+	}
+}
+
+func TestPatchNormalizesTitleOnlyWhenPatched(t *testing.T) {
+	service := newTestService(t, "NORMALIZE_TITLES=true")
+	router := NewRouter(NewTaskHandler(service), service.config)
+	task := service.store.Create(Task{Title: "a  b"})
+	target := "/tasks/" + strconv.Itoa(task.ID)
+	header := []string{"Content-Type", "application/json-patch+json"}
+
+	serve(router, "PATCH", target, `[{"op": "replace", "path": "/progress", "value": 10}]`, header...)
+	if got, _ := service.store.GetByID(task.ID); got.Title != "a  b" {
+		t.Errorf("title after /progress patch = %q, want it untouched", got.Title)
+	}
+	serve(router, "PATCH", target, `[{"op": "replace", "path": "/title", "value": " c   d "}]`, header...)
+	if got, _ := service.store.GetByID(task.ID); got.Title != "c d" {
+		t.Errorf("title after /title patch = %q, want %q", got.Title, "c d")
+	}
+}
+
+func TestPastDueDatePolicy(t *testing.T) {
+	// Часы сервиса далеко в будущем: срок в прошлом только относительно них
+	clock := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	const pastDue = `"2029-12-31T10:00:00Z"`
+	patchHeader := []string{"Content-Type", "application/json-patch+json"}
+	for _, tt := range []struct {
+		allow string
+		code  int
+	}{{"true", http.StatusCreated}, {"false", http.StatusBadRequest}} {
+		t.Run("ALLOW_PAST_DUE="+tt.allow, func(t *testing.T) {
+			service := newTestService(t, "ALLOW_PAST_DUE="+tt.allow)
+			service.now = func() time.Time { return clock }
+			router := NewRouter(NewTaskHandler(service), service.config)
+
+			w := serve(router, "POST", "/tasks", `{"title": "t", "due_date": `+pastDue+`}`)
+			if w.Code != tt.code {
+				t.Fatalf("create with past due date: status %d, want %d (%s)", w.Code, tt.code, w.Body)
+			}
+			if w.Code == http.StatusCreated {
+				var created Task
+				json.Unmarshal(w.Body.Bytes(), &created)
+				if !created.Overdue {
+					t.Error("task created with a past due date is not overdue")
+				}
+			}
+
+			task := service.store.Create(Task{Title: "u"})
+			target := "/tasks/" + strconv.Itoa(task.ID)
+			w = serve(router, "PATCH", target, `[{"op": "add", "path": "/due_date", "value": `+pastDue+`}]`, patchHeader...)
+			if want := map[string]int{"true": http.StatusOK, "false": http.StatusBadRequest}[tt.allow]; w.Code != want {
+				t.Errorf("PATCH past due date: status %d, want %d (%s)", w.Code, want, w.Body)
+			}
+
+			// Уже просроченную задачу можно править, не трогая срок
+			due := clock.Add(-time.Hour)
+			overdue := service.store.Create(Task{Title: "v", DueDate: &due})
+			w = serve(router, "PATCH", "/tasks/"+strconv.Itoa(overdue.ID), `[{"op": "replace", "path": "/title", "value": "w"}]`, patchHeader...)
+			if w.Code != http.StatusOK {
+				t.Errorf("PATCH title of an overdue task: status %d (%s)", w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func TestPatchTaskAddRemoveAndFailedTest(t *testing.T) {
+	service := newTestService(t)
+	router := NewRouter(NewTaskHandler(service), service.config)
+	due := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	task := service.store.Create(Task{Title: "a", DueDate: &due})
+	target := "/tasks/" + strconv.Itoa(task.ID)
+	header := []string{"Content-Type", "application/json-patch+json"}
+
+	w := serve(router, "PATCH", target, `[
+		{"op":"add","path":"/progress","value":30},
+		{"op":"remove","path":"/due_date"}
+	]`, header...)
+	if w.Code != http.StatusOK {
+		t.Fatalf("add/remove: status %d (%s)", w.Code, w.Body)
+	}
+	patched, _ := service.store.GetByID(task.ID)
+	if patched.Progress != 30 || patched.DueDate != nil {
+		t.Errorf("after add/remove: progress %d, due_date %v; want 30, nil", patched.Progress, patched.DueDate)
+	}
+	if patched.Version != task.Version+1 {
+		t.Errorf("version %d, want %d", patched.Version, task.Version+1)
+	}
+
+	// Неудачный test отменяет весь патч, включая операции до него
+	w = serve(router, "PATCH", target, `[
+		{"op":"replace","path":"/title","value":"b"},
+		{"op":"test","path":"/progress","value":99}
+	]`, header...)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("failed test: status %d, want 409 (%s)", w.Code, w.Body)
+	}
+	if got, _ := service.store.GetByID(task.ID); got != patched {
+		t.Errorf("task changed after failed test: %+v, want %+v", got, patched)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// Заголовок с идентификатором запроса
+const requestIDHeader = "X-Request-ID"
+
+// Пути, для которых REQUIRE_REQUEST_ID не действует: проверки
+// живости оркестратора не передают X-Request-ID
+var requestIDOptional = map[string]bool{"/healthz": true}
+
+// Проставление идентификатора запроса: значение из X-Request-ID
+// сохраняется, при его отсутствии генерируется новое либо,
+// при REQUIRE_REQUEST_ID, запрос отклоняется с 400
+// (кроме путей из requestIDOptional)
+func WithRequestID(next http.Handler, require bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if id == "" {
+			if require && !requestIDOptional[r.URL.Path] {
+				http.Error(w, "Отсутствует заголовок "+requestIDHeader, http.StatusBadRequest)
+				return
+			}
+			id = randomToken()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// Ключ идентификатора запроса в контексте
+type requestIDKey struct{}
+
+// Идентификатор запроса из контекста; пустая строка вне обработки запроса
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Случайный идентификатор из 16 байт в hex
+func randomToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
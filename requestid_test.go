@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithRequestID(t *testing.T) {
+	var seen string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestIDFrom(r.Context())
+	})
+	for _, require := range []bool{false, true} {
+		wrapped := WithRequestID(handler, require)
+
+		// Переданный клиентом идентификатор сохраняется
+		seen = ""
+		w := serve(wrapped, "GET", "/tasks", "", requestIDHeader, "client-1")
+		if w.Code != http.StatusOK || seen != "client-1" || w.Header().Get(requestIDHeader) != "client-1" {
+			t.Errorf("require=%v with header: status %d, context %q, header %q", require, w.Code, seen, w.Header().Get(requestIDHeader))
+		}
+
+		seen = ""
+		w = serve(wrapped, "GET", "/tasks", "")
+		if require {
+			if w.Code != http.StatusBadRequest || seen != "" {
+				t.Errorf("require=true without header: status %d, handler called %v; want 400", w.Code, seen != "")
+			}
+		} else if generated := w.Header().Get(requestIDHeader); len(generated) != 32 || seen != generated {
+			t.Errorf("generated ID %q, context %q; want the same 32 hex chars", generated, seen)
+		}
+
+		// Проверка живости обходится без заголовка в любом режиме
+		seen = ""
+		w = serve(wrapped, "GET", "/healthz", "")
+		if w.Code != http.StatusOK || seen == "" || seen != w.Header().Get(requestIDHeader) {
+			t.Errorf("require=%v /healthz: status %d, context %q, header %q", require, w.Code, seen, w.Header().Get(requestIDHeader))
+		}
+	}
+
+	first := serve(WithRequestID(handler, false), "GET", "/tasks", "").Header().Get(requestIDHeader)
+	second := serve(WithRequestID(handler, false), "GET", "/tasks", "").Header().Get(requestIDHeader)
+	if first == second {
+		t.Errorf("generated IDs repeat: %q", first)
+	}
+	if id := requestIDFrom(httptest.NewRequest("GET", "/", nil).Context()); id != "" {
+		t.Errorf("requestIDFrom outside a request = %q, want empty", id)
+	}
+}
+
+func TestRequireRequestIDExemptsHealthz(t *testing.T) {
+	service := newTestService(t, "REQUIRE_REQUEST_ID=true")
+	router := NewRouter(NewTaskHandler(service), service.config)
+	if w := serve(router, "GET", "/healthz", ""); w.Code != http.StatusOK {
+		t.Errorf("/healthz without X-Request-ID: status %d, want 200", w.Code)
+	}
+	if w := serve(router, "GET", "/tasks", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("/tasks without X-Request-ID: status %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"container/list"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Максимум одновременно хранимых снимков
+const snapshotCapacity = 16
+
+// Неизменяемая копия задач для согласованного чтения в нескольких запросах
+type Snapshot struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Tasks     []Task    `json:"tasks"`
+}
+
+// Снимки по токенам с вытеснением давно не использованных (LRU)
+// и удалением по истечении TTL
+type SnapshotStore struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	order *list.List // Элементы *Snapshot, в начале — недавно использованные
+	items map[string]*list.Element
+	now   func() time.Time
+}
+
+// Конструктор хранилища снимков
+func NewSnapshotStore(ttl time.Duration) *SnapshotStore {
+	return &SnapshotStore{
+		ttl:   ttl,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+		now:   time.Now,
+	}
+}
+
+// Сохранение новой копии задач под случайным токеном
+func (s *SnapshotStore) Put(tasks []Task) Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := &Snapshot{Token: randomToken(), ExpiresAt: s.now().Add(s.ttl).UTC(), Tasks: tasks}
+	s.items[snapshot.Token] = s.order.PushFront(snapshot)
+	for s.order.Len() > snapshotCapacity {
+		s.remove(s.order.Back())
+	}
+	return *snapshot
+}
+
+// Снимок по токену; истёкший снимок удаляется
+func (s *SnapshotStore) Get(token string) (Snapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, exists := s.items[token]
+	if !exists {
+		return Snapshot{}, false
+	}
+	snapshot := element.Value.(*Snapshot)
+	if !s.now().Before(snapshot.ExpiresAt) {
+		s.remove(element)
+		return Snapshot{}, false
+	}
+	s.order.MoveToFront(element)
+	return *snapshot, true
+}
+
+func (s *SnapshotStore) remove(element *list.Element) {
+	delete(s.items, element.Value.(*Snapshot).Token)
+	s.order.Remove(element)
+}
+
+// Обработчик GET /tasks/snapshot
+// Последующие запросы GET /tasks?snapshot=<token> читают эту копию,
+// не видя изменений, сделанных после её создания
+func (h *TaskHandler) CreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	snapshot := h.service.snapshots.Put(h.service.store.GetAll(TaskFilter{}))
+	// Асинхронное логирование
+	h.service.Log(r.Context(), "Создан снимок задач: "+strconv.Itoa(len(snapshot.Tasks)))
+	// Формирование ответа
+	snapshot.Tasks = h.service.presentAll(filterTasks(snapshot.Tasks, parseTaskFilter(r)))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshot)
+}
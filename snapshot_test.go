@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSnapshotIsolationAndExpiry(t *testing.T) {
+	for model := range storageModels {
+		t.Run(model, func(t *testing.T) {
+			service := newTestService(t, "STORAGE_MODEL="+model, "SNAPSHOT_TTL=1m")
+			router := NewRouter(NewTaskHandler(service), service.config)
+			clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			service.snapshots.now = func() time.Time { return clock }
+			service.store.Create(Task{Title: "a"})
+			service.store.Create(Task{Title: "b"})
+
+			w := serve(router, "GET", "/tasks/snapshot", "")
+			var snapshot Snapshot
+			if err := json.Unmarshal(w.Body.Bytes(), &snapshot); err != nil || snapshot.Token == "" {
+				t.Fatalf("snapshot %s: %v", w.Body, err)
+			}
+			if !snapshot.ExpiresAt.Equal(clock.Add(time.Minute)) {
+				t.Errorf("expires_at = %v, want %v", snapshot.ExpiresAt, clock.Add(time.Minute))
+			}
+
+			// Изменения после снимка в нём не видны
+			service.store.Update(1, func(task *Task) error {
+				task.Title = "changed"
+				task.Completed = true
+				return nil
+			})
+			service.store.Create(Task{Title: "c"})
+			target := "/tasks?snapshot=" + snapshot.Token
+			w = serve(router, "GET", target, "")
+			var tasks []Task
+			if err := json.Unmarshal(w.Body.Bytes(), &tasks); err != nil {
+				t.Fatalf("GET %s: %s", target, w.Body)
+			}
+			if len(tasks) != 2 || tasks[0].Title != "a" || tasks[0].Completed || tasks[1].Title != "b" {
+				t.Errorf("snapshot tasks = %+v, want a and b as they were", tasks)
+			}
+			if ids := listIDs(t, router, target+"&completed=true"); len(ids) != 0 {
+				t.Errorf("snapshot filtered by completed=true: IDs %v, want none", ids)
+			}
+
+			clock = clock.Add(time.Minute - time.Nanosecond)
+			if w := serve(router, "GET", target, ""); w.Code != http.StatusOK {
+				t.Errorf("just before TTL: status %d, want 200", w.Code)
+			}
+			clock = clock.Add(time.Nanosecond)
+			if w := serve(router, "GET", target, ""); w.Code != http.StatusGone {
+				t.Errorf("after TTL: status %d, want 410", w.Code)
+			}
+			if w := serve(router, "GET", "/tasks?snapshot=unknown", ""); w.Code != http.StatusGone {
+				t.Errorf("unknown token: status %d, want 410", w.Code)
+			}
+		})
+	}
+}
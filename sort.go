@@ -0,0 +1,101 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Направления сортировки
+const (
+	sortAsc  = "asc"
+	sortDesc = "desc"
+)
+
+// Функции сравнения задач по полям, доступным для сортировки
+var sortFields = map[string]func(a, b Task) int{
+	"id":         func(a, b Task) int { return cmp.Compare(a.ID, b.ID) },
+	"title":      func(a, b Task) int { return strings.Compare(a.Title, b.Title) },
+	"created_at": func(a, b Task) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"progress":   func(a, b Task) int { return cmp.Compare(a.Progress, b.Progress) },
+	"order":      func(a, b Task) int { return cmp.Compare(a.Order, b.Order) },
+	"due_date": func(a, b Task) int {
+		// Задачи без срока идут после задач со сроком
+		switch {
+		case a.DueDate == nil || b.DueDate == nil:
+			return cmp.Compare(boolToInt(a.DueDate == nil), boolToInt(b.DueDate == nil))
+		default:
+			return a.DueDate.Compare(*b.DueDate)
+		}
+	},
+}
+
+// Направления по умолчанию, если order не указан
+var defaultSortOrders = map[string]string{
+	"id":         sortAsc,
+	"title":      sortAsc,
+	"created_at": sortDesc,
+	"progress":   sortDesc,
+	"order":      sortAsc,
+	"due_date":   sortAsc,
+}
+
+// Разбор переопределений направлений вида "created_at:asc,title:desc"
+// поверх значений по умолчанию
+func ParseSortDefaults(value string) (map[string]string, error) {
+	orders := make(map[string]string, len(defaultSortOrders))
+	for field, order := range defaultSortOrders {
+		orders[field] = order
+	}
+	if value == "" {
+		return orders, nil
+	}
+	for _, part := range strings.Split(value, ",") {
+		field, order, _ := strings.Cut(strings.TrimSpace(part), ":")
+		if _, ok := sortFields[field]; !ok {
+			return nil, fmt.Errorf("неизвестное поле сортировки %q", field)
+		}
+		if order != sortAsc && order != sortDesc {
+			return nil, fmt.Errorf("неизвестное направление сортировки %q для %s", order, field)
+		}
+		orders[field] = order
+	}
+	return orders, nil
+}
+
+// Поле и направление сортировки из параметров запроса:
+// по умолчанию сортировка по ID, а явное направление order
+// важнее направления поля по умолчанию
+func parseSort(query url.Values, defaults map[string]string) (field, order string, err error) {
+	field = cmp.Or(query.Get("sort"), "id")
+	if _, ok := sortFields[field]; !ok {
+		return "", "", fmt.Errorf("неизвестное поле сортировки %q", field)
+	}
+	order = cmp.Or(query.Get("order"), defaults[field])
+	if order != sortAsc && order != sortDesc {
+		return "", "", fmt.Errorf("неизвестное направление сортировки %q", order)
+	}
+	return field, order, nil
+}
+
+// Сортировка задач по полю и направлению, проверенным parseSort
+func sortTasks(tasks []Task, field, order string) {
+	compare := sortFields[field]
+	if order == sortDesc {
+		asc := compare
+		compare = func(a, b Task) int { return asc(b, a) }
+	}
+	// ID как второй ключ делает порядок одинаковых значений стабильным
+	slices.SortFunc(tasks, func(a, b Task) int {
+		return cmp.Or(compare(a, b), cmp.Compare(a.ID, b.ID))
+	})
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
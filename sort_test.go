@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	defaults, err := ParseSortDefaults("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	overridden, err := ParseSortDefaults("created_at:asc, title:desc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query       string
+		defaults    map[string]string
+		field, want string
+	}{
+		{"", defaults, "id", sortAsc},
+		{"sort=title", defaults, "title", sortAsc},
+		{"sort=created_at", defaults, "created_at", sortDesc},
+		{"sort=progress", defaults, "progress", sortDesc},
+		{"sort=due_date", defaults, "due_date", sortAsc},
+		{"sort=order", defaults, "order", sortAsc},
+		// Явный order важнее направления по умолчанию
+		{"sort=created_at&order=asc", defaults, "created_at", sortAsc},
+		{"sort=title&order=desc", defaults, "title", sortDesc},
+		{"order=desc", defaults, "id", sortDesc},
+		// SORT_DEFAULTS меняет только указанные поля
+		{"sort=created_at", overridden, "created_at", sortAsc},
+		{"sort=title", overridden, "title", sortDesc},
+		{"sort=progress", overridden, "progress", sortDesc},
+		{"sort=title&order=asc", overridden, "title", sortAsc},
+	}
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		field, order, err := parseSort(query, tt.defaults)
+		if err != nil || field != tt.field || order != tt.want {
+			t.Errorf("%q: got %s %s (%v), want %s %s", tt.query, field, order, err, tt.field, tt.want)
+		}
+	}
+	for _, bad := range []string{"sort=owner", "sort=id&order=up", "order=ASC"} {
+		query, _ := url.ParseQuery(bad)
+		if _, _, err := parseSort(query, defaults); err == nil {
+			t.Errorf("%q: no error", bad)
+		}
+	}
+}
+
+func TestParseSortDefaultsRejectsUnknown(t *testing.T) {
+	for _, value := range []string{"owner:asc", "title:up", "title"} {
+		if _, err := ParseSortDefaults(value); err == nil {
+			t.Errorf("ParseSortDefaults(%q): no error", value)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"sync/atomic"
+	"time"
+)
+
+// Число сегментов снимка: после пачки изменений копируются только
+// сегменты с изменёнными задачами, то есть около n/actorShards задач
+// на каждый затронутый сегмент вместо всего хранилища
+const actorShards = 256
+
+// Изменение состояния, выполняемое горутиной-писателем
+type actorCommand struct {
+	apply func(state *TaskStorage)
+	done  chan struct{} // Закрывается после публикации снимка с изменением
+}
+
+// Хранилище с единственным писателем (STORAGE_MODEL=actor).
+// Все изменения выполняются одной горутиной, получающей команды
+// из канала, а чтения обслуживаются из неизменяемого снимка,
+// который публикуется после каждой пачки команд. Читатели
+// не конкурируют с писателем за блокировку.
+type ActorStorage struct {
+	commands chan actorCommand
+	state    *TaskStorage                  // Рабочее состояние, доступно только писателю
+	snapshot atomic.Pointer[actorSnapshot] // Последний опубликованный снимок
+}
+
+// Неизменяемый снимок хранилища. Задачи разбиты на сегменты по ID;
+// следующий снимок разделяет с предыдущим все сегменты, кроме изменённых.
+type actorSnapshot struct {
+	shards    [actorShards]map[int]Task
+	total     int // Количество задач
+	completed int // Количество выполненных задач
+	nextID    int
+	modified  time.Time
+	sequence  uint64
+}
+
+// Конструктор хранилища; запускает горутину-писателя
+func NewActorStorage() *ActorStorage {
+	s := &ActorStorage{
+		commands: make(chan actorCommand, 256),
+		state:    NewTaskStorage(),
+	}
+	s.state.changed = make(map[int]struct{})
+	snapshot := &actorSnapshot{nextID: s.state.nextID}
+	for i := range snapshot.shards {
+		snapshot.shards[i] = map[int]Task{}
+	}
+	s.snapshot.Store(snapshot)
+	go s.run()
+	return s
+}
+
+func shardOf(id int) int {
+	return int(uint(id) % actorShards)
+}
+
+// Цикл писателя: команды, накопившиеся в канале, применяются пачкой,
+// затем публикуется один снимок и ожидающие вызовы освобождаются.
+func (s *ActorStorage) run() {
+	var pending []chan struct{}
+	for cmd := range s.commands {
+		cmd.apply(s.state)
+		pending = append(pending, cmd.done)
+	drain:
+		for {
+			select {
+			case cmd := <-s.commands:
+				cmd.apply(s.state)
+				pending = append(pending, cmd.done)
+			default:
+				break drain
+			}
+		}
+		s.publish()
+		for _, done := range pending {
+			close(done)
+		}
+		pending = pending[:0]
+	}
+}
+
+// Публикация снимка с изменениями пачки: копируются только сегменты,
+// содержащие изменённые задачи (state.changed), остальные переиспользуются
+func (s *ActorStorage) publish() {
+	previous := s.snapshot.Load()
+	next := *previous
+	var copied [actorShards]bool
+	for id := range s.state.changed {
+		shard := shardOf(id)
+		if !copied[shard] {
+			next.shards[shard] = maps.Clone(previous.shards[shard])
+			copied[shard] = true
+		}
+		if task, exists := s.state.tasks[id]; exists {
+			next.shards[shard][id] = task
+		} else {
+			delete(next.shards[shard], id)
+		}
+	}
+	clear(s.state.changed)
+	next.total = len(s.state.tasks)
+	next.completed = s.state.completed
+	next.nextID = s.state.nextID
+	next.modified = s.state.modified
+	next.sequence = s.state.sequence
+	s.snapshot.Store(&next)
+}
+
+// Выполнение изменения писателем с ожиданием публикации результата,
+// чтобы последующее чтение видело собственную запись
+func (s *ActorStorage) exec(apply func(state *TaskStorage)) {
+	done := make(chan struct{})
+	s.commands <- actorCommand{apply, done}
+	<-done
+}
+
+func (s *ActorStorage) Create(task Task) Task {
+	var created Task
+	s.exec(func(state *TaskStorage) { created = state.Create(task) })
+	return created
+}
+
+func (s *ActorStorage) Update(id int, fn func(task *Task) error) (Task, error) {
+	var (
+		updated Task
+		err     error
+	)
+	s.exec(func(state *TaskStorage) { updated, err = state.Update(id, fn) })
+	return updated, err
+}
+
+func (s *ActorStorage) Import(tasks []Task, policy ConflictPolicy) ImportCounts {
+	var counts ImportCounts
+	s.exec(func(state *TaskStorage) { counts = state.Import(tasks, policy) })
+	return counts
+}
+
+func (s *ActorStorage) CompleteBatch(items []VersionedID) []BulkCompleteResult {
+	var results []BulkCompleteResult
+	s.exec(func(state *TaskStorage) { results = state.CompleteBatch(items) })
+	return results
+}
+
+func (s *ActorStorage) GetByID(id int) (Task, bool) {
+	task, exists := s.snapshot.Load().shards[shardOf(id)][id]
+	return task, exists
+}
+
+func (s *ActorStorage) GetAll(filter TaskFilter) []Task {
+	result := []Task{}
+	for _, shard := range s.snapshot.Load().shards {
+		for _, task := range shard {
+			if filter.Matches(task) {
+				result = append(result, task)
+			}
+		}
+	}
+	return result
+}
+
+func (s *ActorStorage) Count(completed *bool) int {
+	snapshot := s.snapshot.Load()
+	switch {
+	case completed == nil:
+		return snapshot.total
+	case *completed:
+		return snapshot.completed
+	default:
+		return snapshot.total - snapshot.completed
+	}
+}
+
+func (s *ActorStorage) LastModified() time.Time {
+	return s.snapshot.Load().modified
+}
+
+func (s *ActorStorage) Sequence() uint64 {
+	return s.snapshot.Load().sequence
+}
+
+func (s *ActorStorage) NextID() int {
+	return s.snapshot.Load().nextID
+}
+
+func (s *ActorStorage) GroupBy(filter TaskFilter, key func(Task) string) map[string][]Task {
+	result := map[string][]Task{}
+	for _, shard := range s.snapshot.Load().shards {
+		for _, task := range shard {
+			if filter.Matches(task) {
+				k := key(task)
+				result[k] = append(result[k], task)
+			}
+		}
+	}
+	return result
+}
+
+func (s *ActorStorage) Statuses(ids []int) map[int]TaskStatus {
+	snapshot := s.snapshot.Load()
+	result := make(map[int]TaskStatus, len(ids))
+	for _, id := range ids {
+		if task, exists := snapshot.shards[shardOf(id)][id]; exists {
+			result[id] = TaskStatus{task.Completed, task.Version}
+		}
+	}
+	return result
+}
+
+// Хранилище в памяти всегда доступно
+func (s *ActorStorage) Ping(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+const benchmarkTasks = 10000
+
+// Параллельные изменения случайных задач в заполненном хранилище
+func benchmarkWrite(b *testing.B, store Storage) {
+	for range benchmarkTasks {
+		store.Create(Task{Title: "t"})
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			id := 1 + rand.IntN(benchmarkTasks)
+			store.Update(id, func(task *Task) error {
+				task.Progress = (task.Progress + 1) % (maxProgress + 1)
+				return nil
+			})
+		}
+	})
+}
+
+func BenchmarkMutexWrite(b *testing.B) {
+	benchmarkWrite(b, NewTaskStorage())
+}
+
+func BenchmarkActorWrite(b *testing.B) {
+	benchmarkWrite(b, NewActorStorage())
+}
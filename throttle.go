@@ -0,0 +1,94 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Порог размера таблицы, после которого удаляются устаревшие записи
+const logThrottleMaxKeys = 1024
+
+// Ограничение частоты одинаковых сообщений лога
+type LogThrottle struct {
+	mu       sync.Mutex
+	interval time.Duration
+	entries  map[LogEntry]*logThrottleEntry
+	now      func() time.Time
+	flush    func(key LogEntry, suppressed int) // Запись итога подавленных повторов
+}
+
+// Время последней записи сообщения и число подавленных повторов
+type logThrottleEntry struct {
+	last       time.Time
+	suppressed int
+}
+
+// Конструктор ограничителя; interval <= 0 отключает ограничение.
+// Если за интервал были подавлены повторы, по его окончании вызывается
+// flush с их числом, даже если сообщение больше не повторяется.
+func NewLogThrottle(interval time.Duration, flush func(key LogEntry, suppressed int)) *LogThrottle {
+	return &LogThrottle{
+		interval: interval,
+		entries:  make(map[LogEntry]*logThrottleEntry),
+		now:      time.Now,
+		flush:    flush,
+	}
+}
+
+// Решение, нужно ли записать запись лога. Повторами считаются записи
+// с тем же уровнем и сообщением независимо от запроса.
+// Вместе с разрешением возвращается число повторов,
+// подавленных с момента предыдущей записи.
+func (t *LogThrottle) Allow(entry LogEntry) (bool, int) {
+	if t.interval <= 0 {
+		return true, 0
+	}
+	key := LogEntry{Level: entry.Level, Message: entry.Message}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	state, exists := t.entries[key]
+	if exists && now.Sub(state.last) < t.interval {
+		state.suppressed++
+		if state.suppressed == 1 {
+			time.AfterFunc(state.last.Add(t.interval).Sub(now), func() { t.flushEntry(key, state) })
+		}
+		return false, 0
+	}
+	if !exists {
+		t.prune(now)
+		state = &logThrottleEntry{}
+		t.entries[key] = state
+	}
+	suppressed := state.suppressed
+	state.last, state.suppressed = now, 0
+	return true, suppressed
+}
+
+// Итог подавленных повторов по окончании интервала. Итог считается
+// записью сообщения: следующий интервал отсчитывается от него.
+// Если сообщение уже записано повторно с итогом, счётчик пуст.
+func (t *LogThrottle) flushEntry(key LogEntry, entry *logThrottleEntry) {
+	t.mu.Lock()
+	suppressed := entry.suppressed
+	if suppressed > 0 {
+		entry.last, entry.suppressed = t.now(), 0
+	}
+	t.mu.Unlock()
+	if suppressed > 0 && t.flush != nil {
+		t.flush(key, suppressed)
+	}
+}
+
+// Удаление записей, интервал которых истёк
+func (t *LogThrottle) prune(now time.Time) {
+	if len(t.entries) < logThrottleMaxKeys {
+		return
+	}
+	for key, entry := range t.entries {
+		if now.Sub(entry.last) >= t.interval {
+			delete(t.entries, key)
+		}
+	}
+}
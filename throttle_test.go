@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLogThrottleFlushesSuppressedCount(t *testing.T) {
+	config, _, err := LoadConfig([]string{"LOG_THROTTLE_INTERVAL=100ms"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logChan := make(chan LogEntry, 100)
+	service := NewTaskService(NewTaskStorage(), logChan, nil, config)
+	t.Cleanup(func() { service.CloseLog() })
+
+	for range 10 {
+		service.Log(context.Background(), "Задача не найдена: #7")
+	}
+	service.Log(context.Background(), "Задача не найдена: #8")
+	for _, want := range []string{"Задача не найдена: #7", "Задача не найдена: #8"} {
+		if got := (<-logChan).Message; got != want {
+			t.Fatalf("entry = %q, want %q", got, want)
+		}
+	}
+	select {
+	case entry := <-logChan:
+		t.Fatalf("unexpected entry within the interval: %q", entry.Message)
+	default:
+	}
+
+	// Повторов больше нет, но итог всё равно записывается по окончании интервала
+	select {
+	case entry := <-logChan:
+		if got, want := entry.Message, "Задача не найдена: #7 (подавлено повторов: 9)"; got != want {
+			t.Errorf("flushed entry = %q, want %q", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("suppressed count was not flushed after the interval")
+	}
+
+	// Итог считается записью: повтор сразу после него подавляется
+	// и попадает в следующий итог
+	service.Log(context.Background(), "Задача не найдена: #7")
+	select {
+	case entry := <-logChan:
+		if got, want := entry.Message, "Задача не найдена: #7 (подавлено повторов: 1)"; got != want {
+			t.Errorf("second flush = %q, want %q", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second suppressed count was not flushed")
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Пути, для которых Server-Timing не добавляется
+var serverTimingExcluded = map[string]bool{"/healthz": true, "/version": true}
+
+// Заголовок Server-Timing с временем обработки запроса (SERVER_TIMING).
+// Заголовки уходят клиенту вместе с первым байтом ответа, поэтому
+// для потоковых ответов (экспорт) время считается до начала отправки.
+func WithServerTiming(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serverTimingExcluded[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(&timingWriter{ResponseWriter: w, start: time.Now()}, r)
+	})
+}
+
+// Обёртка ответа, проставляющая Server-Timing перед отправкой заголовков
+type timingWriter struct {
+	http.ResponseWriter
+	start       time.Time
+	wroteHeader bool
+}
+
+func (w *timingWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		dur := float64(time.Since(w.start).Microseconds()) / 1000
+		w.Header().Set("Server-Timing", "app;dur="+strconv.FormatFloat(dur, 'f', 1, 64))
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timingWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Потоковая отправка экспорта проверяет http.Flusher
+func (w *timingWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *timingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+func TestServerTiming(t *testing.T) {
+	header := regexp.MustCompile(`^app;dur=\d+\.\d$`)
+	service := newTestService(t, "SERVER_TIMING=true", "ENABLE_UI=true")
+	router := NewRouter(NewTaskHandler(service), service.config)
+	service.store.Create(Task{Title: "a"})
+
+	for _, tt := range []struct{ method, target, body string }{
+		{"GET", "/tasks", ""},
+		{"GET", "/tasks/1", ""},
+		{"GET", "/tasks/404", ""},
+		{"POST", "/tasks", `{"title": "b"}`},
+		{"GET", "/tasks/export", ""}, // Потоковый ответ
+		{"GET", "/ui", ""},
+	} {
+		w := serve(router, tt.method, tt.target, tt.body)
+		if got := w.Header().Get("Server-Timing"); !header.MatchString(got) {
+			t.Errorf("%s %s (%d): Server-Timing %q, want app;dur=<ms>", tt.method, tt.target, w.Code, got)
+		}
+	}
+	for _, target := range []string{"/healthz", "/version"} {
+		w := serve(router, "GET", target, "")
+		if w.Code != http.StatusOK || w.Header().Get("Server-Timing") != "" {
+			t.Errorf("GET %s: status %d, Server-Timing %q; want 200 without the header", target, w.Code, w.Header().Get("Server-Timing"))
+		}
+	}
+
+	disabled := newTestService(t)
+	if got := serve(NewRouter(NewTaskHandler(disabled), disabled.config), "GET", "/tasks", "").Header().Get("Server-Timing"); got != "" {
+		t.Errorf("without SERVER_TIMING: Server-Timing %q", got)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// Шаблон страницы со списком задач; html/template экранирует
+// все подставляемые значения, поэтому названия задач безопасны
+var uiTemplate = template.Must(template.New("ui").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>Задачи</title>
+</head>
+<body>
+<h1>Задачи ({{len .}})</h1>
+<table>
+<tr><th>ID</th><th>Название</th><th>Выполнена</th><th>Срок</th><th>Прогресс</th></tr>
+{{range .}}<tr>
+<td>{{.ID}}</td>
+<td>{{.Title}}</td>
+<td>{{if .Completed}}да{{else}}нет{{end}}</td>
+<td>{{with .DueDate}}{{.Format "2006-01-02 15:04"}}{{end}}{{if .Overdue}} (просрочена){{end}}</td>
+<td>{{.Progress}}%</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// Обработчик GET /ui (включается ENABLE_UI)
+func (h *TaskHandler) TaskListPage(w http.ResponseWriter, r *http.Request) {
+	tasks := h.service.presentAll(h.service.store.GetAll(parseTaskFilter(r)))
+	slices.SortFunc(tasks, func(a, b Task) int { return a.ID - b.ID })
+	// Асинхронное логирование
+	h.service.Log(r.Context(), "Запрос страницы задач: найдено "+strconv.Itoa(len(tasks)))
+	// Формирование ответа
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := uiTemplate.Execute(w, tasks); err != nil {
+		h.service.LogWarn(r.Context(), "Ошибка отображения страницы задач: "+err.Error())
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTaskListPage(t *testing.T) {
+	service := newTestService(t, "ENABLE_UI=true")
+	router := NewRouter(NewTaskHandler(service), service.config)
+	service.store.Create(Task{Title: "Купить молоко"})
+	service.store.Create(Task{Title: `<script>alert("x")</script> & co`})
+
+	w := serve(router, "GET", "/ui", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d (%s)", w.Code, w.Body)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"<h1>Задачи (2)</h1>",
+		"<td>Купить молоко</td>",
+		"<td>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; &amp; co</td>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page does not contain %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "<script>") {
+		t.Errorf("title rendered unescaped:\n%s", body)
+	}
+
+	disabled := newTestService(t)
+	if w := serve(NewRouter(NewTaskHandler(disabled), disabled.config), "GET", "/ui", ""); w.Code != http.StatusNotFound {
+		t.Errorf("without ENABLE_UI: status %d, want 404", w.Code)
+	}
+}
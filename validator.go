@@ -0,0 +1,134 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Ошибка проверки поля задачи
+type FieldError struct {
+	Field   string `json:"field"`   // Имя поля в JSON
+	Message string `json:"message"` // Описание нарушения
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Все нарушения, найденные при проверке задачи
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Правила проверки задач с учётом текущих настроек
+type TaskValidator struct {
+	allowPastDue     bool             // Разрешать срок выполнения в прошлом
+	allowBlankTitles bool             // Разрешать названия только из пробелов
+	warnDueDays      int              // Предупреждать о сроке дальше N дней (0 — выкл.)
+	warnTitleLength  int              // Предупреждать о названии длиннее N символов (0 — выкл.)
+	now              func() time.Time // Источник текущего времени
+}
+
+// Конструктор валидатора
+func NewTaskValidator(config Config, now func() time.Time) *TaskValidator {
+	return &TaskValidator{
+		allowPastDue:     config.AllowPastDue,
+		allowBlankTitles: config.AllowBlankTitles,
+		warnDueDays:      config.WarnDueDays,
+		warnTitleLength:  config.WarnTitleLength,
+		now:              now,
+	}
+}
+
+// Проверка новой задачи
+func (v *TaskValidator) ValidateCreate(task Task) ValidationErrors {
+	errs := v.validateFields(task)
+	errs = append(errs, v.validateDueDate(task)...)
+	return errs
+}
+
+// Наибольший ID импортируемой задачи. Запас до math.MaxInt нужен,
+// чтобы счётчик следующего ID не переполнился и новые задачи
+// оставались адресуемыми через /tasks/{id}.
+const maxImportID = math.MaxInt32
+
+// Проверка импортируемой задачи: дополнительно проверяется
+// сохраняемое время создания (нулевое заменяется текущим)
+func (v *TaskValidator) ValidateImport(task Task) ValidationErrors {
+	errs := v.ValidateCreate(task)
+	if task.ID > maxImportID {
+		errs = append(errs, FieldError{"id", "ID не должен превышать " + strconv.Itoa(maxImportID)})
+	}
+	if !task.CreatedAt.IsZero() && (task.CreatedAt.Before(time.Unix(0, 0)) || task.CreatedAt.After(v.now())) {
+		errs = append(errs, FieldError{"created_at", "время создания должно быть между 1970 годом и текущим моментом"})
+	}
+	return errs
+}
+
+// Проверка изменённой задачи; срок выполнения проверяется,
+// только если он изменился, чтобы уже просроченная задача
+// оставалась редактируемой
+func (v *TaskValidator) ValidateUpdate(old, updated Task) ValidationErrors {
+	errs := v.validateFields(updated)
+	if !equalTimes(old.DueDate, updated.DueDate) {
+		errs = append(errs, v.validateDueDate(updated)...)
+	}
+	return errs
+}
+
+// Правила, не зависящие от вида операции
+func (v *TaskValidator) validateFields(task Task) ValidationErrors {
+	var errs ValidationErrors
+	if task.Title == "" {
+		errs = append(errs, FieldError{"title", "название задачи обязательно"})
+	} else if strings.TrimSpace(task.Title) == "" && !v.allowBlankTitles {
+		errs = append(errs, FieldError{"title", "название задачи не может состоять только из пробелов"})
+	}
+	if task.Progress < minProgress || task.Progress > maxProgress {
+		errs = append(errs, FieldError{"progress", "прогресс должен быть от 0 до 100"})
+	}
+	return errs
+}
+
+// Проверка срока выполнения согласно политике ALLOW_PAST_DUE
+func (v *TaskValidator) validateDueDate(task Task) ValidationErrors {
+	if task.DueDate != nil && task.DueDate.Before(v.now()) && !v.allowPastDue {
+		return ValidationErrors{{"due_date", "срок выполнения в прошлом"}}
+	}
+	return nil
+}
+
+// Некритичные замечания к сохранённой задаче: запрос не отклоняется,
+// а замечания возвращаются клиенту в поле warnings
+func (v *TaskValidator) warn(task Task) []string {
+	var warnings []string
+	if v.warnDueDays > 0 && task.DueDate != nil && task.DueDate.After(v.now().AddDate(0, 0, v.warnDueDays)) {
+		warnings = append(warnings, "срок выполнения позже чем через "+strconv.Itoa(v.warnDueDays)+" дн.")
+	}
+	if v.warnTitleLength > 0 && utf8.RuneCountInString(task.Title) > v.warnTitleLength {
+		warnings = append(warnings, "название длиннее "+strconv.Itoa(v.warnTitleLength)+" символов")
+	}
+	return warnings
+}
+
+// Задача в ответе на создание или изменение вместе с замечаниями
+type taskWithWarnings struct {
+	Task
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Ответ с ошибками проверки: 400 или 422 согласно VALIDATION_ERROR_STATUS.
+// Ошибки разбора JSON сюда не попадают и всегда возвращают 400.
+func (h *TaskHandler) writeValidationError(w http.ResponseWriter, errs ValidationErrors) {
+	http.Error(w, "Ошибка валидации: "+errs.Error(), h.service.config.ValidationStatus)
+}
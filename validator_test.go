@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestValidator(t *testing.T, now time.Time, environ ...string) *TaskValidator {
+	t.Helper()
+	config, _, err := LoadConfig(environ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewTaskValidator(config, func() time.Time { return now })
+}
+
+// Поля, о которых сообщили ошибки, в порядке проверки
+func errorFields(errs ValidationErrors) []string {
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	return fields
+}
+
+func TestTaskValidator(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	tests := []struct {
+		name    string
+		environ []string
+		check   func(v *TaskValidator) ValidationErrors
+		want    []string
+	}{
+		{"valid create", nil, func(v *TaskValidator) ValidationErrors {
+			return v.ValidateCreate(Task{Title: "t", Progress: 50, DueDate: &future})
+		}, nil},
+		{"empty title", nil, func(v *TaskValidator) ValidationErrors {
+			return v.ValidateCreate(Task{})
+		}, []string{"title"}},
+		{"blank title", nil, func(v *TaskValidator) ValidationErrors {
+			return v.ValidateCreate(Task{Title: " \t"})
+		}, []string{"title"}},
+		{"blank title allowed", []string{"ALLOW_BLANK_TITLES=true"}, func(v *TaskValidator) ValidationErrors {
+			return v.ValidateCreate(Task{Title: " \t"})
+		}, nil},
+		{"progress below range", nil, func(v *TaskValidator) ValidationErrors {
+			return v.ValidateCreate(Task{Title: "t", Progress: -1})
+		}, []string{"progress"}},
+		{"progress above range", nil, func(v *TaskValidator) ValidationErrors {
+			return v.ValidateCreate(Task{Title: "t", Progress: 101})
+		}, []string{"progress"}},
+		{"past due allowed by default", nil, func(v *TaskValidator) ValidationErrors {
+			return v.ValidateCreate(Task{Title: "t", DueDate: &past})
+		}, nil},
+		{"past due rejected", []string{"ALLOW_PAST_DUE=false"}, func(v *TaskValidator) ValidationErrors {
+			return v.ValidateCreate(Task{Title: "t", DueDate: &past})
+		}, []string{"due_date"}},
+		{"all create rules at once", []string{"ALLOW_PAST_DUE=false"}, func(v *TaskValidator) ValidationErrors {
+			return v.ValidateCreate(Task{Progress: 200, DueDate: &past})
+		}, []string{"title", "progress", "due_date"}},
+		{"update keeps existing past due", []string{"ALLOW_PAST_DUE=false"}, func(v *TaskValidator) ValidationErrors {
+			return v.ValidateUpdate(Task{Title: "t", DueDate: &past}, Task{Title: "t2", DueDate: &past})
+		}, nil},
+		{"update moves due into the past", []string{"ALLOW_PAST_DUE=false"}, func(v *TaskValidator) ValidationErrors {
+			return v.ValidateUpdate(Task{Title: "t", DueDate: &future}, Task{Title: "t", DueDate: &past})
+		}, []string{"due_date"}},
+		{"update with several violations", []string{"ALLOW_PAST_DUE=false"}, func(v *TaskValidator) ValidationErrors {
+			return v.ValidateUpdate(Task{Title: "t"}, Task{Title: " ", Progress: -5, DueDate: &past})
+		}, []string{"title", "progress", "due_date"}},
+		{"import keeps historical created_at", nil, func(v *TaskValidator) ValidationErrors {
+			return v.ValidateImport(Task{ID: 7, Title: "t", CreatedAt: time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)})
+		}, nil},
+		{"import created_at in the future", nil, func(v *TaskValidator) ValidationErrors {
+			return v.ValidateImport(Task{Title: "t", CreatedAt: future})
+		}, []string{"created_at"}},
+		{"import created_at before 1970", nil, func(v *TaskValidator) ValidationErrors {
+			return v.ValidateImport(Task{Title: "t", CreatedAt: time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC)})
+		}, []string{"created_at"}},
+		{"import ID too large", nil, func(v *TaskValidator) ValidationErrors {
+			return v.ValidateImport(Task{ID: maxImportID + 1, Title: "t"})
+		}, []string{"id"}},
+		{"import with create and import violations", nil, func(v *TaskValidator) ValidationErrors {
+			return v.ValidateImport(Task{ID: maxImportID + 1, Progress: 101, CreatedAt: future})
+		}, []string{"title", "progress", "id", "created_at"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := tt.check(newTestValidator(t, now, tt.environ...))
+			if got := errorFields(errs); !slices.Equal(got, tt.want) {
+				t.Errorf("error fields = %q, want %q (%v)", got, tt.want, errs)
+			}
+		})
+	}
+}
+
+func TestValidationErrorStatus(t *testing.T) {
+	patch := []string{"Content-Type", "application/json-patch+json"}
+	tests := []struct {
+		name                 string
+		method, target, body string
+		header               []string
+		validation           bool // Ошибка валидации, а не разбора запроса
+	}{
+		{"blank title", "POST", "/tasks", `{"title": "  "}`, nil, true},
+		{"progress out of range", "POST", "/tasks", `{"title": "t", "progress": 150}`, nil, true},
+		{"past due date", "POST", "/tasks", `{"title": "t", "due_date": "2000-01-01T00:00:00Z"}`, nil, true},
+		{"patched blank title", "PATCH", "/tasks/1", `[{"op": "replace", "path": "/title", "value": ""}]`, patch, true},
+		{"invalid imported task", "POST", "/tasks/import", `[{"title": ""}]`, nil, true},
+		{"malformed JSON", "POST", "/tasks", `{"title": `, nil, false},
+		{"wrong field type", "POST", "/tasks", `{"title": 5}`, nil, false},
+		{"malformed patch", "PATCH", "/tasks/1", `[{"op": "move"}]`, patch, false},
+		{"malformed import", "POST", "/tasks/import", `{}`, nil, false},
+		{"invalid ID", "PATCH", "/tasks/abc", `[]`, patch, false},
+	}
+	for _, mode := range []int{http.StatusBadRequest, http.StatusUnprocessableEntity} {
+		status := strconv.Itoa(mode)
+		service := newTestService(t, "VALIDATION_ERROR_STATUS="+status, "ALLOW_PAST_DUE=false")
+		router := NewRouter(NewTaskHandler(service), service.config)
+		service.store.Create(Task{Title: "t"})
+		for _, tt := range tests {
+			want := http.StatusBadRequest
+			if tt.validation {
+				want = mode
+			}
+			if w := serve(router, tt.method, tt.target, tt.body, tt.header...); w.Code != want {
+				t.Errorf("VALIDATION_ERROR_STATUS=%s %s: status %d, want %d (%s)", status, tt.name, w.Code, want, w.Body)
+			}
+		}
+	}
+}
+
+func TestWhitespaceOnlyTitles(t *testing.T) {
+	titles := []string{" ", "   ", "\t", " \t\n ", "\u00a0", "\u2003\u3000"} // Включая неразрывный и широкие пробелы
+	patch := []string{"Content-Type", "application/json-patch+json"}
+	for _, allow := range []bool{false, true} {
+		mode := "ALLOW_BLANK_TITLES=" + strconv.FormatBool(allow)
+		service := newTestService(t, mode)
+		router := NewRouter(NewTaskHandler(service), service.config)
+		existing := service.store.Create(Task{Title: "t"})
+		for _, title := range titles {
+			quoted := strconv.Quote(title)
+			create := serve(router, "POST", "/tasks", `{"title": `+quoted+`}`)
+			update := serve(router, "PATCH", "/tasks/"+strconv.Itoa(existing.ID), `[{"op": "replace", "path": "/title", "value": `+quoted+`}]`, patch...)
+			imported := serve(router, "POST", "/tasks/import", `[{"title": `+quoted+`}]`)
+			if !allow {
+				if create.Code != http.StatusBadRequest || update.Code != http.StatusBadRequest || imported.Code != http.StatusBadRequest {
+					t.Errorf("%s %s: create %d, patch %d, import %d; want 400", mode, quoted, create.Code, update.Code, imported.Code)
+				}
+				continue
+			}
+			var created Task
+			json.Unmarshal(create.Body.Bytes(), &created)
+			if create.Code != http.StatusCreated || created.Title != title {
+				t.Errorf("%s %s: create %d, title %q; want 201 and the title as is", mode, quoted, create.Code, created.Title)
+			}
+			if got, _ := service.store.GetByID(existing.ID); update.Code != http.StatusOK || got.Title != title {
+				t.Errorf("%s %s: patch %d, title %q; want 200 and the title as is", mode, quoted, update.Code, got.Title)
+			}
+			if imported.Code != http.StatusOK {
+				t.Errorf("%s %s: import %d, want 200", mode, quoted, imported.Code)
+			}
+		}
+		// Пустое название недопустимо в любом режиме
+		if w := serve(router, "POST", "/tasks", `{"title": ""}`); w.Code != http.StatusBadRequest {
+			t.Errorf("%s empty title: status %d, want 400", mode, w.Code)
+		}
+	}
+}
+
+func TestWarningsKeepTaskPersisted(t *testing.T) {
+	clock := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	service := newTestService(t, "WARN_DUE_DAYS=30", "WARN_TITLE_LENGTH=5")
+	service.now = func() time.Time { return clock }
+	router := NewRouter(NewTaskHandler(service), service.config)
+	decode := func(body []byte) taskWithWarnings {
+		t.Helper()
+		var response taskWithWarnings
+		if err := json.Unmarshal(body, &response); err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+
+	w := serve(router, "POST", "/tasks", `{"title": "долгое название", "due_date": "2027-01-01T00:00:00Z"}`)
+	created := decode(w.Body.Bytes())
+	if w.Code != http.StatusCreated || len(created.Warnings) != 2 {
+		t.Fatalf("create: status %d, warnings %q; want 201 with 2 warnings", w.Code, created.Warnings)
+	}
+	if stored, ok := service.store.GetByID(created.ID); !ok || stored.Title != "долгое название" {
+		t.Errorf("task with warnings not stored: %+v", stored)
+	}
+
+	patch := []string{"Content-Type", "application/json-patch+json"}
+	w = serve(router, "PATCH", "/tasks/"+strconv.Itoa(created.ID), `[{"op": "replace", "path": "/due_date", "value": null}]`, patch...)
+	updated := decode(w.Body.Bytes())
+	if w.Code != http.StatusOK || !slices.Equal(updated.Warnings, []string{"название длиннее 5 символов"}) {
+		t.Errorf("patch: status %d, warnings %q; want 200 with the title warning", w.Code, updated.Warnings)
+	}
+	if stored, _ := service.store.GetByID(created.ID); stored.DueDate != nil || stored.Version != 2 {
+		t.Errorf("patch with warnings not stored: %+v", stored)
+	}
+
+	// Без замечаний поле warnings опускается
+	w = serve(router, "POST", "/tasks", `{"title": "ok"}`)
+	if strings.Contains(w.Body.String(), "warnings") {
+		t.Errorf("response without warnings: %s", w.Body)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Сведения о сборке, задаются при сборке:
+// go build -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse --short HEAD) -X main.buildTime=$(date -u +%FT%TZ)"
+var version, commit, buildTime string
+
+// Сведения о сборке; незаданные значения заменяются на "dev"
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+}
+
+func currentBuildInfo() BuildInfo {
+	orDev := func(value string) string {
+		if value == "" {
+			return "dev"
+		}
+		return value
+	}
+	return BuildInfo{orDev(version), orDev(commit), orDev(buildTime)}
+}
+
+// Обработчик GET /version
+func (h *TaskHandler) Version(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(currentBuildInfo())
+}
+
+// Добавление заголовка X-App-Version ко всем ответам
+func WithVersionHeader(next http.Handler) http.Handler {
+	appVersion := currentBuildInfo().Version
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-App-Version", appVersion)
+		next.ServeHTTP(w, r)
+	})
+}
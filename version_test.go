@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestVersionEndpoint(t *testing.T) {
+	for _, tt := range []struct {
+		name                   string
+		version, commit, built string
+		want                   BuildInfo
+	}{
+		{"defaults", "", "", "", BuildInfo{"dev", "dev", "dev"}},
+		{"ldflags", "1.2.0", "abc1234", "2026-01-02T03:04:05Z", BuildInfo{"1.2.0", "abc1234", "2026-01-02T03:04:05Z"}},
+		{"partial", "1.2.0", "", "", BuildInfo{"1.2.0", "dev", "dev"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := [3]string{version, commit, buildTime}
+			version, commit, buildTime = tt.version, tt.commit, tt.built
+			t.Cleanup(func() { version, commit, buildTime = saved[0], saved[1], saved[2] })
+
+			service := newTestService(t, "VERSION_HEADER=true")
+			w := serve(NewRouter(NewTaskHandler(service), service.config), "GET", "/version", "")
+			var got BuildInfo
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("GET /version: %v (%s)", err, w.Body)
+			}
+			if got != tt.want {
+				t.Errorf("GET /version = %+v, want %+v", got, tt.want)
+			}
+			if header := w.Header().Get("X-App-Version"); header != tt.want.Version {
+				t.Errorf("X-App-Version = %q, want %q", header, tt.want.Version)
+			}
+		})
+	}
+	service := newTestService(t)
+	w := serve(NewRouter(NewTaskHandler(service), service.config), "GET", "/version", "")
+	if header, ok := w.Header()["X-App-Version"]; ok {
+		t.Errorf("X-App-Version = %q without VERSION_HEADER", header)
+	}
+}